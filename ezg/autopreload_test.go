@@ -1,29 +1,62 @@
 package ezg
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// autoPreloadNames returns names of autoPreloads.
+func autoPreloadNames(model interface{}) ([]string, error) {
+	specs, err := autoPreloads(model, nil)
+	return specNames(specs), err
+}
+
+func specNames(specs []preloadSpec) []string {
+	out := make([]string, len(specs))
+	for i := range specs {
+		out[i] = specs[i].name
+	}
+	return out
+}
 
 func Test_AutopreloadingFields(t *testing.T) {
 	type ChldT4NoPreload struct {
-		Foo string
+		ID       uint
+		ChldT3ID uint
+		Foo      string
 	}
 	type ChldT3 struct {
+		ID          uint
+		ChldT2ID    uint
 		Foo         string
 		NoPreloads  []ChldT4NoPreload `ezg:"no-preload"`
-		NoPreloads2 []ChldT4NoPreload `ezg:"nopreload"`
+		NoPreloads2 []ChldT4NoPreload `ezg:"nopreload" gorm:"foreignKey:ChldT3ID"`
 	}
 	type ChldT2 struct {
+		ID         uint
+		ChldT1ID   uint
 		ChildrenT3 []*ChldT3
 	}
 	type ChldT1 struct {
+		ID         uint
+		ParentID   uint
 		ChildrenT2 []ChldT2
 	}
 	type Parent struct {
+		ID       uint
 		Children []*ChldT1
 	}
 	want := []string{
 		"Children", "Children.ChildrenT2", "Children.ChildrenT2.ChildrenT3",
 	}
-	got := autoPreloads(&Parent{})
+	got, err := autoPreloadNames(&Parent{})
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(want) != len(got) {
 		t.Fatalf("wanted %d results, but got %d results", len(want), len(got))
 		return
@@ -35,3 +68,189 @@ func Test_AutopreloadingFields(t *testing.T) {
 		}
 	}
 }
+
+func Test_AutopreloadingSingularRelations(t *testing.T) {
+	type Owner struct {
+		ID   uint
+		Name string
+	}
+	type Profile struct {
+		ID     uint
+		UserID uint
+		Bio    string
+	}
+	type Item struct {
+		ID      uint
+		UserID  uint
+		OwnerID uint
+		Owner   *Owner
+	}
+	type User struct {
+		ID        uint
+		CreatedAt time.Time
+		DeletedAt gorm.DeletedAt
+		Address   struct{ Street string } `gorm:"embedded"`
+		Tags      []string                `gorm:"serializer:json"`
+		Profile   Profile
+		Items     []Item
+	}
+	want := []string{"Profile", "Items", "Items.Owner"}
+	got, err := autoPreloadNames(&User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("wanted %v, but got %v", want, got)
+	}
+}
+
+type cyclicCategory struct {
+	ID       uint
+	ParentID *uint
+	Children []cyclicCategory `gorm:"foreignKey:ParentID"`
+	Tree     []cyclicCategory `gorm:"foreignKey:ParentID" ezg:"preload-depth=3"`
+}
+
+type cyclicAuthor struct {
+	ID      uint
+	Posts   []cyclicPost   `gorm:"foreignKey:AuthorID"`
+	Friends []cyclicAuthor `gorm:"many2many:cyclic_friends"`
+}
+
+type cyclicPost struct {
+	ID       uint
+	AuthorID uint
+	Author   cyclicAuthor
+}
+
+func Test_AutopreloadingCycles(t *testing.T) {
+	for _, tc := range []struct {
+		model interface{}
+		want  []string
+	}{
+		{&cyclicCategory{}, []string{"Tree", "Tree.Tree", "Tree.Tree.Tree"}},
+		{&cyclicAuthor{}, []string{"Posts"}},
+		{&cyclicPost{}, []string{"Author"}},
+	} {
+		got, err := autoPreloadNames(tc.model)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%T: wanted %v, but got %v", tc.model, tc.want, got)
+		}
+	}
+
+	type badDepth struct {
+		ID       uint
+		ParentID uint
+		Children []badDepth `gorm:"foreignKey:ParentID" ezg:"preload-depth=0"`
+	}
+	if _, err := autoPreloadNames(&badDepth{}); !errors.Is(err, ErrPreloadTag) {
+		t.Fatalf("expected ErrPreloadTag, got %v", err)
+	}
+
+	// limit is per parent, which makes sense only for has-many
+	type badLimit struct {
+		ID       uint
+		AuthorID uint
+		Author   cyclicAuthor `ezg:"limit=1"`
+	}
+	if _, err := autoPreloadNames(&badLimit{}); !errors.Is(err, ErrPreloadTag) {
+		t.Fatalf("expected ErrPreloadTag, got %v", err)
+	}
+}
+
+type benchRoot struct {
+	ID       uint
+	Sections []benchSection
+	Owner    benchUser `gorm:"foreignKey:OwnerID"`
+	OwnerID  uint
+}
+
+type benchSection struct {
+	ID          uint
+	BenchRootID uint
+	Pages       []benchPage
+	Editors     []benchUser `gorm:"many2many:bench_section_editors"`
+}
+
+type benchPage struct {
+	ID             uint
+	BenchSectionID uint
+	Blocks         []benchBlock
+	Author         benchUser `gorm:"foreignKey:AuthorID"`
+	AuthorID       uint
+}
+
+type benchBlock struct {
+	ID          uint
+	BenchPageID uint
+	Assets      []benchAsset
+}
+
+type benchAsset struct {
+	ID           uint
+	BenchBlockID uint
+	Uploader     benchUser `gorm:"foreignKey:UploaderID"`
+	UploaderID   uint
+}
+
+type benchUser struct {
+	ID      uint
+	Profile benchProfile
+}
+
+type benchProfile struct {
+	ID          uint
+	BenchUserID uint
+}
+
+func Test_AutopreloadingCached(t *testing.T) {
+	specs, err := walkPreloads(&benchRoot{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := specNames(specs)
+	for i := 0; i < 2; i++ {
+		got, err := autoPreloadNames(&benchRoot{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("wanted %v, but got %v", want, got)
+		}
+	}
+	if len(want) != 12 {
+		t.Fatalf("expected 12 preloads of deep model, got %v", want)
+	}
+}
+
+func Benchmark_AutopreloadsUncached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := walkPreloads(&benchRoot{}, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_AutopreloadsCached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := autoPreloads(&benchRoot{}, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_AutopreloadsCachedParallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := autoPreloads(&benchRoot{}, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -4,71 +4,183 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-)
+	"sync"
 
-const (
-	maxRecursion = 200
-	nopreload1   = "no-preload"
-	nopreload2   = "nopreload"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-type fieldInfo struct {
-	name string
-	typ  reflect.Type
+const maxRecursion = 200
+
+// schemas caches gorm schemas parsed without db (by Validate and PreloadPlan), with default naming strategy. Column
+// and table names of these schemas may differ from the ones of the db, so they are good only for relation shape and
+// field names, never for SQL.
+var schemas = &sync.Map{}
+
+// plans caches results of autoPreloads per model type and gorm config.
+var plans = &sync.Map{}
+
+// schemaKey identifies model type within gorm config, whose naming strategy determines column and table names of the
+// model. Config is nil for models resolved without db.
+type schemaKey struct {
+	typ    reflect.Type
+	config *gorm.Config
 }
 
-func autoPreloads(model interface{}) []string {
-	return autoPreloadsInternal(model, "", 0)
+func schemaKeyOf(db *gorm.DB, model interface{}) schemaKey {
+	typ := reflect.TypeOf(model)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	key := schemaKey{typ: typ}
+	if db != nil {
+		key.config = db.Config
+	}
+	return key
 }
 
-func autoPreloadsInternal(model interface{}, prefix string, i uint) []string {
-	if i > maxRecursion {
-		panic(fmt.Sprintf("max recursion treshold of %d exceeded. Infinitely recursive preloads are not supported.", maxRecursion))
+// parseSchema parses model with naming strategy and schema cache of db, or with default naming strategy if db is nil.
+func parseSchema(db *gorm.DB, model interface{}) (*schema.Schema, error) {
+	if db == nil {
+		return schema.Parse(model, schemas, schema.NamingStrategy{})
 	}
-	flds := exportedFields(model)
-	out := make([]string, 0)
-	for _, fld := range flds {
-		if fld.typ.Kind() == reflect.Slice {
-			out = append(out, prefix+fld.name)
-			out = append(out, autoPreloadsInternal(fld.typ, prefix+fld.name+".", i+1)...)
-		}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
 	}
-	return out
+	return stmt.Schema, nil
+}
+
+type preloadPlan struct {
+	specs []preloadSpec
+	err   error
 }
 
-func exportedFields(i interface{}) []fieldInfo {
-	typ, ok := i.(reflect.Type)
-	if !ok {
-		typ = reflect.TypeOf(i)
+// autoPreloads returns relations of the model (has-one, belongs-to, has-many, many2many and polymorphic, as
+// recognized by gorm, including relations of embedded structs), followed by relations of the related models,
+// recursively. Relations leading to a model that is already being preloaded on the same path (such as
+// Category.Children or Post.Author of Author.Posts) close a cycle and are skipped, unless the relation field is tagged
+// with ezg:"preload-depth=N", in which case it is followed up to N times. Conditions of the preloads come from ezg
+// tags of the relation fields (see relationTag).
+// Relations are resolved with naming strategy of db (nil db means default naming, which is enough for validation).
+// Result is computed once per model type and gorm config, returned slice is shared and must not be modified.
+func autoPreloads(model interface{}, db *gorm.DB) ([]preloadSpec, error) {
+	key := schemaKeyOf(db, model)
+	if p, ok := plans.Load(key); ok {
+		return p.(*preloadPlan).specs, p.(*preloadPlan).err
 	}
-	for typ.Kind() != reflect.Struct {
-		switch typ.Kind() {
-		case reflect.Ptr:
-			typ = typ.Elem()
-		case reflect.Slice, reflect.Array:
-			typ = typ.Elem()
-		default:
-			return make([]fieldInfo, 0)
-		}
+	specs, err := walkPreloads(model, db, nil)
+	p, _ := plans.LoadOrStore(key, &preloadPlan{specs: specs, err: err})
+	return p.(*preloadPlan).specs, p.(*preloadPlan).err
+}
+
+// walkPreloads computes result of autoPreloads. If skipped is not nil, fields that are not preloaded are recorded in
+// it.
+func walkPreloads(model interface{}, db *gorm.DB, skipped *[]SkippedPreload) ([]preloadSpec, error) {
+	s, err := parseSchema(db, model)
+	if err != nil {
+		return nil, &PreloadError{Model: modelName(model), Err: err, Detail: "relations of the model can't be resolved"}
 	}
+	w := &preloadWalk{
+		root:    modelName(model),
+		types:   map[*schema.Schema]int{s: 1},
+		edges:   make(map[*schema.Relationship]int),
+		skipped: skipped,
+	}
+	return w.walk(s, "", true, 0)
+}
 
-	fields := make([]fieldInfo, 0)
+// preloadWalk tracks models and relations on the path being walked by the autopreloader.
+type preloadWalk struct {
+	root    string
+	types   map[*schema.Schema]int
+	edges   map[*schema.Relationship]int
+	skipped *[]SkippedPreload
+}
 
-	// Loop over struct fields.
-	for i := 0; i < typ.NumField(); i++ {
-		tag, ok := typ.Field(i).Tag.Lookup("ezg")
-		if ok && (strings.EqualFold(tag, nopreload1) || strings.EqualFold(tag, nopreload2)) {
+// walk returns preloads of relations of s. joined tells whether s itself is root model or joined relation, so that
+// its relations can be joined too.
+func (w *preloadWalk) walk(s *schema.Schema, prefix string, joined bool, i uint) ([]preloadSpec, error) {
+	if i > maxRecursion {
+		return nil, &PreloadError{Model: w.root, Err: ErrPreloadTooDeep, Detail: fmt.Sprintf(
+			"max recursion treshold of %d exceeded following relation %s. Infinitely recursive preloads are not "+
+				"supported.", maxRecursion, strings.SplitN(prefix, ".", 2)[0])}
+	}
+	out := make([]preloadSpec, 0)
+	for _, field := range s.Fields {
+		name := prefix + fieldPath(field)
+		rel, ok := s.Relationships.Relations[field.Name]
+		if !ok || rel.Field != field {
+			// fields without column, which are not relations either, are most likely mistaken for relations, unless
+			// they hold association counts
+			if field.DBName == "" && !isCountField(field) {
+				w.skip(name, SkipNotRelation)
+			}
 			continue
 		}
-		// Only take exported fields (name starts with an uppercase letter).
-		if typ.Field(i).PkgPath == "" {
-			field := fieldInfo{
-				name: typ.Field(i).Name,
-				typ:  typ.Field(i).Type,
-			}
-			fields = append(fields, field)
+		tag, err := parseRelationTag(rel.Field.StructField)
+		var cond func(orm *gorm.DB) *gorm.DB
+		if err == nil {
+			cond, err = tag.condition(rel)
+		}
+		if err != nil {
+			return nil, &PreloadError{Model: w.root, Err: ErrPreloadTag, Detail: fmt.Sprintf(
+				"ezg tag of %s.%s: %v", s.Name, rel.Name, err)}
+		}
+		switch {
+		case tag.noPreload:
+			w.skip(name, SkipNoPreloadTag)
+			continue
+		case tag.depth > 0 && w.edges[rel] >= tag.depth:
+			w.skip(name, SkipDepthLimit)
+			continue
+		case tag.depth == 0 && w.types[rel.FieldSchema] > 0:
+			w.skip(name, SkipCycle)
+			continue
+		}
+
+		spec := preloadSpec{name: name, cond: cond, source: SourceAutopreload}
+		spec.join = tag.join && joined && joinable(rel) && spec.cond == nil
+		if spec.cond != nil || tag.depth > 0 || tag.join {
+			spec.source = SourceTag
+		}
+		out = append(out, spec)
+		w.types[rel.FieldSchema]++
+		w.edges[rel]++
+		nested, err := w.walk(rel.FieldSchema, name+".", spec.join, i+1)
+		w.types[rel.FieldSchema]--
+		w.edges[rel]--
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, nested...)
 	}
+	return out, nil
+}
+
+// joinable reports whether relation can be preloaded by JOIN, which gorm supports for to-one relations.
+func joinable(rel *schema.Relationship) bool {
+	return (rel.Type == schema.BelongsTo || rel.Type == schema.HasOne) && rel.Polymorphic == nil &&
+		len(rel.Field.EmbeddedBindNames) <= 1
+}
 
-	return fields
+func (w *preloadWalk) skip(path string, reason SkipReason) {
+	if w.skipped != nil {
+		*w.skipped = append(*w.skipped, SkippedPreload{Path: path, Reason: reason})
+	}
+}
+
+// isCountField reports whether field is tagged to hold association count.
+func isCountField(field *schema.Field) bool {
+	tag, err := parseRelationTag(field.StructField)
+	return err == nil && tag.count != ""
+}
+
+// fieldPath returns path of the field as used by gorm Preload. Fields of structs embedded with gorm:"embedded" are
+// qualified by the embedding field (Meta.Editor), fields of anonymous structs are not.
+func fieldPath(field *schema.Field) string {
+	if len(field.EmbeddedBindNames) > 1 {
+		return strings.Join(field.EmbeddedBindNames, ".")
+	}
+	return field.Name
 }
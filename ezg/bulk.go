@@ -0,0 +1,100 @@
+package ezg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultBatchSize = 1000
+
+// InsertOptions configure InsertMany. Zero value (or nil) inserts the models without associations, in batches of
+// 1000.
+type InsertOptions struct {
+	// BatchSize is number of models inserted by single INSERT statement, 0 means 1000.
+	BatchSize int
+	// Associations inserts associations of the models along with them, like gorm Create does. By default they are
+	// omitted, which is what bulk loads usually want and what keeps every batch a single statement.
+	Associations bool
+	// Progress, if not nil, is called after every batch with number of models inserted so far and total number of
+	// models. As the whole insert runs in one transaction, inserted models are not visible to others until it ends.
+	Progress func(done, total int)
+}
+
+// InsertMany inserts models in batches (like gorm CreateInBatches), in single transaction (or savepoint, if db is a
+// transaction already), so that either every model is inserted or none is. Generated primary keys are set on the
+// models and also returned, one per model, in the form FindByIDs accepts (value of the key, or []interface{} for
+// composite keys):
+// keys, err := ezg.InsertMany(orm, tasks, &ezg.InsertOptions{BatchSize: 500})
+// If the model implements InsertOverrider, the override is called for every model instead, still in single
+// transaction, with progress reported every BatchSize models. Errors are *QueryError, like those of Q.Insert.
+func InsertMany[t any](db *gorm.DB, models []t, opts *InsertOptions) ([]interface{}, error) {
+	if opts == nil {
+		opts = &InsertOptions{}
+	}
+	q := W(new(t))
+	if len(models) == 0 {
+		return make([]interface{}, 0), nil
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	_, overridden := interface{}(q.obj).(InsertOverrider)
+
+	var failed *gorm.DB
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !opts.Associations && !overridden {
+			tx = tx.Omit(clause.Associations)
+		}
+		for start := 0; start < len(models); start += size {
+			end := min(start+size, len(models))
+			if overridden {
+				for i := start; i < end; i++ {
+					if err := W(&models[i]).Insert(tx); err != nil {
+						return err
+					}
+				}
+			} else if failed = tx.Create(models[start:end]); failed.Error != nil {
+				return failed.Error
+			}
+			if opts.Progress != nil {
+				opts.Progress(end, len(models))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, q.fail("InsertMany", failed, err)
+	}
+	keys, err := primaryKeys(db.Statement.Context, db, models)
+	return keys, q.fail("InsertMany", nil, err)
+}
+
+// primaryKeys returns primary key of every model, in the form FindByIDs accepts.
+func primaryKeys[t any](ctx context.Context, db *gorm.DB, models []t) ([]interface{}, error) {
+	s, err := W(new(t)).schema(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("LOGIC ERROR: %w: model %s has no primary key", ErrNotGormModel, s.Name)
+	}
+	keys := make([]interface{}, len(models))
+	for i := range models {
+		rv := reflect.ValueOf(&models[i]).Elem()
+		if len(s.PrimaryFields) == 1 {
+			keys[i], _ = s.PrimaryFields[0].ValueOf(ctx, rv)
+			continue
+		}
+		key := make([]interface{}, len(s.PrimaryFields))
+		for j, field := range s.PrimaryFields {
+			key[j], _ = field.ValueOf(ctx, rv)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
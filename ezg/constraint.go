@@ -0,0 +1,115 @@
+package ezg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ConstraintError is a database constraint violation, classified independently of the driver, so that handlers can
+// map it to a response without knowing the database:
+//
+//	if errors.Is(err, ErrUniqueViolation) { // 409 }
+//
+// It is wrapped in *QueryError. Names that the database does not report are left empty, e.g. SQLite does not name
+// violated foreign keys and gorm with TranslateError enabled reports no names at all.
+type ConstraintError struct {
+	// Kind is ErrUniqueViolation, ErrForeignKeyViolation, ErrNotNullViolation or ErrCheckViolation.
+	Kind error
+	// Constraint is the name of the violated constraint.
+	Constraint string
+	// Table is the name of the table the constraint belongs to.
+	Table string
+	// Columns are the names of the constrained columns.
+	Columns []string
+	// Err is the error returned by the driver.
+	Err error
+}
+
+func (e *ConstraintError) Error() string {
+	switch {
+	case e.Constraint != "":
+		return fmt.Sprintf("%v: constraint %s", e.Kind, e.Constraint)
+	case len(e.Columns) > 0:
+		return fmt.Sprintf("%v: %s(%s)", e.Kind, e.Table, strings.Join(e.Columns, ", "))
+	}
+	return e.Kind.Error()
+}
+
+// Unwrap returns both Kind and driver error, so errors.Is matches either.
+func (e *ConstraintError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+var (
+	// sqliteConstraint matches messages such as "UNIQUE constraint failed: tasks.title"
+	sqliteConstraint = regexp.MustCompile(`(UNIQUE|FOREIGN KEY|NOT NULL|CHECK) constraint failed(?:: (.+))?$`)
+	// pgKeyDetail matches detail of postgres unique violation, such as "Key (title)=(write docs) already exists."
+	pgKeyDetail = regexp.MustCompile(`^Key \((.+?)\)=`)
+)
+
+// constraintError classifies err as *ConstraintError, or returns it unchanged if it is not a constraint violation.
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		ce := &ConstraintError{Constraint: pgErr.ConstraintName, Table: pgErr.TableName, Err: err}
+		switch pgErr.Code {
+		case "23505":
+			ce.Kind = ErrUniqueViolation
+		case "23503":
+			ce.Kind = ErrForeignKeyViolation
+		case "23502":
+			ce.Kind = ErrNotNullViolation
+		case "23514":
+			ce.Kind = ErrCheckViolation
+		default:
+			return err
+		}
+		if pgErr.ColumnName != "" {
+			ce.Columns = []string{pgErr.ColumnName}
+		} else if m := pgKeyDetail.FindStringSubmatch(pgErr.Detail); m != nil {
+			ce.Columns = strings.Split(m[1], ", ")
+		}
+		return ce
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &ConstraintError{Kind: ErrUniqueViolation, Err: err}
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return &ConstraintError{Kind: ErrForeignKeyViolation, Err: err}
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return &ConstraintError{Kind: ErrCheckViolation, Err: err}
+	}
+
+	// sqlite drivers do not share error type, but they do share messages
+	m := sqliteConstraint.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	ce := &ConstraintError{Err: err}
+	switch m[1] {
+	case "UNIQUE":
+		ce.Kind = ErrUniqueViolation
+	case "FOREIGN KEY":
+		ce.Kind = ErrForeignKeyViolation
+	case "NOT NULL":
+		ce.Kind = ErrNotNullViolation
+	case "CHECK":
+		// sqlite reports name of the constraint for checks, not columns
+		ce.Kind = ErrCheckViolation
+		ce.Constraint = m[2]
+		return ce
+	}
+	for _, col := range strings.Split(m[2], ", ") {
+		if table, name, ok := strings.Cut(col, "."); ok {
+			ce.Table = table
+			ce.Columns = append(ce.Columns, name)
+		}
+	}
+	return ce
+}
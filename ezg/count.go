@@ -0,0 +1,264 @@
+package ezg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// countsKey is the gorm setting carrying count fields of the model from preloading to the finished finder.
+const countsKey = "ezg:counts"
+
+// counts caches results of countFields per model type and gorm config.
+var counts = &sync.Map{}
+
+type countPlan struct {
+	fields []countField
+	err    error
+}
+
+// countField is a field of the model filled with number of records of an association, such as
+// PostsCount int `gorm:"-" ezg:"count=Posts"`
+type countField struct {
+	field *schema.Field
+	rel   *schema.Relationship
+}
+
+// countFields returns fields of the model tagged with ezg:"count=Association", with associations resolved by naming
+// strategy of db, as their tables and foreign keys are used in the count query. Result is computed once per model
+// type and gorm config, returned slice is shared and must not be modified.
+func countFields(model interface{}, db *gorm.DB) ([]countField, error) {
+	key := schemaKeyOf(db, model)
+	if p, ok := counts.Load(key); ok {
+		return p.(*countPlan).fields, p.(*countPlan).err
+	}
+	fields, err := parseCountFields(model, db)
+	p, _ := counts.LoadOrStore(key, &countPlan{fields: fields, err: err})
+	return p.(*countPlan).fields, p.(*countPlan).err
+}
+
+func parseCountFields(model interface{}, db *gorm.DB) ([]countField, error) {
+	s, err := parseSchema(db, model)
+	if err != nil {
+		return nil, &PreloadError{Model: modelName(model), Err: err, Detail: "relations of the model can't be resolved"}
+	}
+	out := make([]countField, 0)
+	for _, field := range s.Fields {
+		if rel, ok := s.Relationships.Relations[field.Name]; ok && rel.Field == field {
+			continue
+		}
+		tag, err := parseRelationTag(field.StructField)
+		if err == nil && tag.count == "" {
+			continue
+		}
+		if err == nil {
+			err = countable(s, field, tag.count)
+		}
+		if err != nil {
+			return nil, &PreloadError{Model: s.Name, Err: ErrCountTag, Detail: fmt.Sprintf(
+				"ezg tag of %s: %v", field.Name, err)}
+		}
+		rel, _ := relationAt(s, tag.count)
+		out = append(out, countField{field: field, rel: rel})
+	}
+	return out, nil
+}
+
+// countable checks that field can hold number of records of association path.
+func countable(s *schema.Schema, field *schema.Field, path string) error {
+	switch field.IndirectFieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return fmt.Errorf("count field must be integer, got %s", field.FieldType)
+	}
+	rel, err := relationAt(s, path)
+	if err != nil {
+		return err
+	}
+	_, _, err = countKey(rel)
+	return err
+}
+
+// countKey returns field of the parent model that the association references and column holding its value in
+// the count query.
+func countKey(rel *schema.Relationship) (*schema.Field, clause.Column, error) {
+	if rel.Type != schema.HasMany && rel.Type != schema.HasOne && rel.Type != schema.Many2Many {
+		return nil, clause.Column{}, fmt.Errorf("%w: %s is %s relation", ErrNotCountable, rel.Name, rel.Type)
+	}
+	var parent *schema.Field
+	var key clause.Column
+	for _, ref := range rel.References {
+		if !ref.OwnPrimaryKey {
+			continue
+		}
+		if parent != nil {
+			return nil, clause.Column{}, fmt.Errorf("%w: %s has composite foreign key", ErrNotCountable, rel.Name)
+		}
+		parent = ref.PrimaryKey
+		key = clause.Column{Table: clause.CurrentTable, Name: ref.ForeignKey.DBName}
+		if rel.JoinTable != nil {
+			key.Table = rel.JoinTable.Table
+		}
+	}
+	if parent == nil {
+		return nil, clause.Column{}, fmt.Errorf("%w: %s has no foreign key", ErrNotCountable, rel.Name)
+	}
+	return parent, key, nil
+}
+
+// associationCounts counts records of association rel per parent key, in single query grouped by foreign key.
+// Soft deleted records are not counted. Parents without records are missing in the result.
+func associationCounts(db *gorm.DB, rel *schema.Relationship, keys []interface{}) (map[interface{}]int, *gorm.DB, error) {
+	out := make(map[interface{}]int)
+	parent, key, err := countKey(rel)
+	if err != nil || len(keys) == 0 {
+		return out, nil, err
+	}
+	qry := db.Model(reflect.New(rel.FieldSchema.ModelType).Interface())
+	if rel.JoinTable != nil {
+		on := make([]string, 0)
+		vars := []interface{}{clause.Table{Name: rel.JoinTable.Table}}
+		for _, ref := range rel.References {
+			if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
+				on = append(on, "? = ?")
+				vars = append(vars, clause.Column{Table: rel.JoinTable.Table, Name: ref.ForeignKey.DBName},
+					clause.Column{Table: clause.CurrentTable, Name: ref.PrimaryKey.DBName})
+			}
+		}
+		qry = qry.Joins("JOIN ? ON "+strings.Join(on, " AND "), vars...)
+	}
+	for _, ref := range rel.References {
+		if ref.PrimaryValue != "" {
+			// polymorphic type, or other fixed value of the association
+			table := clause.CurrentTable
+			if rel.JoinTable != nil {
+				table = rel.JoinTable.Table
+			}
+			qry = qry.Where(clause.Eq{Column: clause.Column{Table: table, Name: ref.ForeignKey.DBName}, Value: ref.PrimaryValue})
+		}
+	}
+
+	tx := qry.Select("?, COUNT(*)", key).Where(clause.IN{Column: key, Values: keys}).
+		Clauses(clause.GroupBy{Columns: []clause.Column{key}})
+	rows, err := tx.Rows()
+	if err != nil {
+		return out, tx, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		k := reflect.New(parent.IndirectFieldType)
+		n := 0
+		if err = rows.Scan(k.Interface(), &n); err != nil {
+			return out, tx, err
+		}
+		out[k.Elem().Interface()] = n
+	}
+	return out, tx, rows.Err()
+}
+
+// CountAssociation counts records of association (such as "Posts", relation name of the wrapped model) of every parent
+// given by its key, which is primary key of the wrapped model or other field referenced by the association, in single
+// query, without loading the records:
+// counts, err := W(&Author{}).CountAssociation(orm, "Posts", a.ID, b.ID)
+// postsOfA := counts[a.ID]
+// Parents without records are missing in the result. Has-many, has-one and many2many relations can be counted, other
+// return ErrNotCountable. To fill counts on retrieved models, tag integer field of the model instead:
+// PostsCount int `gorm:"-" ezg:"count=Posts"`
+func (q Q[t]) CountAssociation(db *gorm.DB, association string, keys ...interface{}) (map[interface{}]int, error) {
+	db = q.session(db)
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, q.fail("CountAssociation", nil, err)
+	}
+	rel, err := relationAt(s, association)
+	if err != nil {
+		return nil, q.fail("CountAssociation", nil, err)
+	}
+	out, tx, err := associationCounts(db, rel, keys)
+	return out, q.fail("CountAssociation", tx, err)
+}
+
+// withCounts marks the query to fill count fields of retrieved models, see counted.
+func (q Q[t]) withCounts(qry *gorm.DB) (*gorm.DB, error) {
+	fields, err := countFields(q.obj, qry)
+	if err != nil || len(fields) == 0 {
+		return qry, err
+	}
+	return qry.Set(countsKey, fields), nil
+}
+
+// counted fills count fields of models (pointer to model or slice of models) retrieved by tx, if the query was
+// marked by withCounts. Every tagged association is counted with single query for all the models.
+func counted(tx *gorm.DB, models reflect.Value) error {
+	if tx == nil {
+		return nil
+	}
+	v, ok := tx.Get(countsKey)
+	if !ok {
+		return nil
+	}
+	elems := make([]reflect.Value, 0)
+	switch models = reflect.Indirect(models); models.Kind() {
+	case reflect.Slice:
+		for i := 0; i < models.Len(); i++ {
+			elems = append(elems, models.Index(i))
+		}
+	case reflect.Struct:
+		elems = append(elems, models)
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+
+	db := tx.Session(&gorm.Session{NewDB: true})
+	ctx := tx.Statement.Context
+	for _, cf := range v.([]countField) {
+		parent, _, _ := countKey(cf.rel)
+		keys := make([]interface{}, 0, len(elems))
+		seen := make(map[interface{}]bool)
+		for _, elem := range elems {
+			if k, ok := countKeyOf(ctx, parent, elem); ok && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+		n, countTx, err := associationCounts(db, cf.rel, keys)
+		if err != nil {
+			qe := &QueryError{Model: cf.field.Schema.Name, Err: err}
+			if countTx != nil {
+				qe.SQL = statementSQL(countTx)
+			}
+			return qe
+		}
+		for _, elem := range elems {
+			k, _ := countKeyOf(ctx, parent, elem)
+			if err = cf.field.Set(ctx, elem, n[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countKeyOf returns value of key field of model, dereferenced so that it matches keys of associationCounts.
+func countKeyOf(ctx context.Context, key *schema.Field, model reflect.Value) (interface{}, bool) {
+	v, zero := key.ValueOf(ctx, model)
+	if zero {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	return rv.Interface(), true
+}
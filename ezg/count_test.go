@@ -0,0 +1,46 @@
+package ezg
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_CountFields(t *testing.T) {
+	type counted struct {
+		ID         uint
+		Posts      []cyclicPost `gorm:"foreignKey:AuthorID"`
+		PostsCount int          `gorm:"-" ezg:"count=Posts"`
+		Note       string       `gorm:"-"`
+	}
+	fields, err := countFields(&counted{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].field.Name != "PostsCount" || fields[0].rel.Name != "Posts" {
+		t.Fatalf("unexpected count fields %+v", fields)
+	}
+	if names, err := autoPreloadNames(&counted{}); err != nil || len(names) != 2 {
+		t.Fatalf("expected count field not to affect preloads, got %v, %v", names, err)
+	}
+
+	type notInteger struct {
+		ID         uint
+		Posts      []cyclicPost `gorm:"foreignKey:AuthorID"`
+		PostsCount string       `gorm:"-" ezg:"count=Posts"`
+	}
+	type unknown struct {
+		ID         uint
+		PostsCount int `gorm:"-" ezg:"count=Posts"`
+	}
+	type belongsTo struct {
+		ID          uint
+		AuthorID    uint
+		Author      cyclicAuthor
+		AuthorCount int `gorm:"-" ezg:"count=Author"`
+	}
+	for _, model := range []interface{}{&notInteger{}, &unknown{}, &belongsTo{}} {
+		if err := Validate(model); !errors.Is(err, ErrCountTag) || !errors.Is(err, ErrInvalidPreload) {
+			t.Fatalf("%T: expected ErrCountTag, got %v", model, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ezg
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotGormModel means that the wrapped model cannot be handled by the generic implementation of the operation:
+	// gorm can't parse it, or it lacks primary key the operation needs (no primary key field, or the key is not set).
+	// Models that are not regular gorm models should implement override of the operation.
+	ErrNotGormModel = errors.New("not a gorm model")
+	// ErrInvalidPreload is matched by every *PreloadError, regardless of the exact inconsistency.
+	ErrInvalidPreload = errors.New("invalid preload definition")
+	// ErrNotFound is returned by single record finders (and Delete) of wrappers in strict mode, when there is no
+	// matching record. Without strict mode, these return nil model and nil error instead.
+	ErrNotFound = errors.New("record not found")
+	// ErrUnknownColumn is returned when a filter or an order references a column that is not part of the wrapped model.
+	ErrUnknownColumn = errors.New("unknown column")
+	// ErrUnknownRelation is returned when With, Without or PreloadOnly reference a relation path that is not part of
+	// the wrapped model.
+	ErrUnknownRelation = errors.New("unknown relation")
+	// ErrInvalidCursor is returned by keyset finders when cursor is malformed, was tampered with, was signed with
+	// different key or was issued for query with different ordering or conditions.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrNoConditions is returned by UpdateWhere and DeleteWhere (and their Sql variants) called without any condition,
+	// which would affect every record, unless allowed with AllowAll.
+	ErrNoConditions = errors.New("no conditions")
+	// ErrUniqueViolation means that a write violated unique constraint (or primary key). See ConstraintError.
+	ErrUniqueViolation = errors.New("unique constraint violation")
+	// ErrForeignKeyViolation means that a write violated foreign key constraint. See ConstraintError.
+	ErrForeignKeyViolation = errors.New("foreign key constraint violation")
+	// ErrNotNullViolation means that a write violated not null constraint. See ConstraintError.
+	ErrNotNullViolation = errors.New("not null constraint violation")
+	// ErrCheckViolation means that a write violated check constraint. See ConstraintError.
+	ErrCheckViolation = errors.New("check constraint violation")
+	// ErrPreloadMismatch means that multi-preload RequiresPreload returned different number of relation names and
+	// gorm functions.
+	ErrPreloadMismatch = errors.New("inconsistent multi-preload definition")
+	// ErrPreloadTooDeep means that autopreloading exceeded maximum recursion depth.
+	ErrPreloadTooDeep = errors.New("preload recursion limit exceeded")
+	// ErrPreloadTag means that ezg struct tag of a relation field holds invalid preload option.
+	ErrPreloadTag = errors.New("invalid preload tag")
+	// ErrCountTag means that ezg count tag of a field is invalid: the field is not integer, or the association does not
+	// exist or can't be counted.
+	ErrCountTag = errors.New("invalid count tag")
+	// ErrNotCountable is returned by CountAssociation for relations that can't be counted per parent (belongs-to,
+	// relations with composite foreign key).
+	ErrNotCountable = errors.New("association can't be counted")
+	// ErrOverrideSignature is reported by Validate for model methods named like an override, which do not match its
+	// signature and therefore are not used as overrides.
+	ErrOverrideSignature = errors.New("override method signature mismatch")
+)
+
+// PreloadError is returned by finders (and Validate) when preloads of a model are declared inconsistently. It is a
+// logic error in the model definition, so every non-shallow read of the model fails with it.
+type PreloadError struct {
+	// Model is the name of the model type.
+	Model string
+	// Err is ErrPreloadMismatch, ErrPreloadTooDeep, ErrPreloadTag, ErrCountTag or error of gorm failing to parse the model.
+	Err error
+	// Detail describes the inconsistency.
+	Detail string
+}
+
+func (e *PreloadError) Error() string {
+	return fmt.Sprintf("LOGIC ERROR: model %s: %v: %s", e.Model, e.Err, e.Detail)
+}
+
+func (e *PreloadError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrInvalidPreload as matching, in addition to Err.
+func (e *PreloadError) Is(target error) bool {
+	return target == ErrInvalidPreload
+}
+
+// QueryError is returned by every method of Q that fails, including failures of model overrides. It wraps the
+// underlying error (constraint violations are classified as *ConstraintError first), so errors.Is works with gorm
+// errors, driver errors and sentinels of this package:
+// if _, err := W(&Task{}).Strict().FindByID(orm, id); errors.Is(err, ErrNotFound) { ... }
+type QueryError struct {
+	// Op is the name of the method that failed, such as "FindOne" or "ShallowFindPage".
+	Op string
+	// Model is the name of the model type.
+	Model string
+	// SQL is the statement that failed, with placeholders instead of values. It is empty if the operation failed
+	// before reaching the database, or failed in a model override.
+	SQL string
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s of model %s failed: %v", e.Op, e.Model, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,200 @@
+package ezg
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type filterOp uint8
+
+const (
+	opNone filterOp = iota
+	opEq
+	opNeq
+	opLt
+	opLte
+	opGt
+	opGte
+	opIn
+	opBetween
+	opLike
+	opIsNull
+	opNotNull
+	opAnd
+	opOr
+	opNot
+)
+
+// Filter is a typed WHERE condition, built with the constructors below (Eq, In, Between, ...), combined with And,
+// Or and Not and attached to the wrapper with Q.Where:
+// tasks, err := W(&Task{}).Where(Or(Eq("done", false), Gt("priority", 3))).Find(orm)
+// Columns can be given either as gorm field names ("CreatedAt") or as database column names ("created_at"). They are
+// validated against the gorm schema of the wrapped model when the query runs, unknown columns fail the query with
+// ErrUnknownColumn instead of reaching the database. Zero value Filter matches everything.
+type Filter struct {
+	op       filterOp
+	column   string
+	values   []interface{}
+	children []Filter
+}
+
+// Eq matches rows where column = value.
+func Eq(column string, value interface{}) Filter {
+	return Filter{op: opEq, column: column, values: []interface{}{value}}
+}
+
+// Neq matches rows where column != value.
+func Neq(column string, value interface{}) Filter {
+	return Filter{op: opNeq, column: column, values: []interface{}{value}}
+}
+
+// Lt matches rows where column < value.
+func Lt(column string, value interface{}) Filter {
+	return Filter{op: opLt, column: column, values: []interface{}{value}}
+}
+
+// Lte matches rows where column <= value.
+func Lte(column string, value interface{}) Filter {
+	return Filter{op: opLte, column: column, values: []interface{}{value}}
+}
+
+// Gt matches rows where column > value.
+func Gt(column string, value interface{}) Filter {
+	return Filter{op: opGt, column: column, values: []interface{}{value}}
+}
+
+// Gte matches rows where column >= value.
+func Gte(column string, value interface{}) Filter {
+	return Filter{op: opGte, column: column, values: []interface{}{value}}
+}
+
+// In matches rows where column is one of values. Empty values match nothing. Slice and array values (other than
+// []byte) are flattened, so that typed slices can be passed as is: In("id", ids).
+func In(column string, values ...interface{}) Filter {
+	flat := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		rv := reflect.ValueOf(value)
+		if _, bytes := value.([]byte); bytes || rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			flat = append(flat, value)
+			continue
+		}
+		for i := 0; i < rv.Len(); i++ {
+			flat = append(flat, rv.Index(i).Interface())
+		}
+	}
+	return Filter{op: opIn, column: column, values: flat}
+}
+
+// Between matches rows where column BETWEEN low AND high (inclusive).
+func Between(column string, low, high interface{}) Filter {
+	return Filter{op: opBetween, column: column, values: []interface{}{low, high}}
+}
+
+// Like matches rows where column LIKE pattern.
+func Like(column string, pattern string) Filter {
+	return Filter{op: opLike, column: column, values: []interface{}{pattern}}
+}
+
+// IsNull matches rows where column IS NULL.
+func IsNull(column string) Filter {
+	return Filter{op: opIsNull, column: column}
+}
+
+// NotNull matches rows where column IS NOT NULL.
+func NotNull(column string) Filter {
+	return Filter{op: opNotNull, column: column}
+}
+
+// And matches rows matching all filters.
+func And(filters ...Filter) Filter {
+	return Filter{op: opAnd, children: filters}
+}
+
+// Or matches rows matching at least one of filters.
+func Or(filters ...Filter) Filter {
+	return Filter{op: opOr, children: filters}
+}
+
+// Not matches rows that do not match all of filters, that is NOT (a AND b), regardless of the filters.
+func Not(filters ...Filter) Filter {
+	return Filter{op: opNot, children: filters}
+}
+
+// build resolves the filter against s. It returns nil expression for filters that do not restrict anything.
+func (f Filter) build(s *schema.Schema) (clause.Expression, error) {
+	switch f.op {
+	case opNone:
+		return nil, nil
+	case opAnd, opOr, opNot:
+		exprs := make([]clause.Expression, 0, len(f.children))
+		for i := range f.children {
+			expr, err := f.children[i].build(s)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				exprs = append(exprs, expr)
+			}
+		}
+		if len(exprs) == 0 {
+			return nil, nil
+		}
+		switch {
+		case f.op == opNot && len(exprs) == 1:
+			// built directly, as clause.Not unwraps And and negates comparisons one by one, so that
+			// NOT (a = x AND b = y) would become a <> x AND b <> y
+			return clause.NotConditions{Exprs: exprs}, nil
+		case f.op == opNot:
+			return clause.NotConditions{Exprs: []clause.Expression{clause.AndConditions{Exprs: exprs}}}, nil
+		case len(exprs) == 1:
+			// single element Or is treated by gorm as "OR <expr>" glued to preceding condition, unwrap it
+			return exprs[0], nil
+		case f.op == opOr:
+			return clause.OrConditions{Exprs: exprs}, nil
+		default:
+			return clause.AndConditions{Exprs: exprs}, nil
+		}
+	}
+
+	col, err := column(s, f.column)
+	if err != nil {
+		return nil, err
+	}
+	switch f.op {
+	case opEq:
+		return clause.Eq{Column: col, Value: f.values[0]}, nil
+	case opNeq:
+		return clause.Neq{Column: col, Value: f.values[0]}, nil
+	case opLt:
+		return clause.Lt{Column: col, Value: f.values[0]}, nil
+	case opLte:
+		return clause.Lte{Column: col, Value: f.values[0]}, nil
+	case opGt:
+		return clause.Gt{Column: col, Value: f.values[0]}, nil
+	case opGte:
+		return clause.Gte{Column: col, Value: f.values[0]}, nil
+	case opIn:
+		return clause.IN{Column: col, Values: f.values}, nil
+	case opBetween:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{col, f.values[0], f.values[1]}}, nil
+	case opLike:
+		return clause.Like{Column: col, Value: f.values[0]}, nil
+	case opIsNull:
+		return clause.Eq{Column: col, Value: nil}, nil
+	case opNotNull:
+		return clause.Neq{Column: col, Value: nil}, nil
+	}
+	return nil, fmt.Errorf("LOGIC ERROR: unsupported filter operation %d", f.op)
+}
+
+// column resolves field name or column name to a column of the model's table.
+func column(s *schema.Schema, name string) (clause.Column, error) {
+	field := s.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return clause.Column{}, fmt.Errorf("%w %q in model %s", ErrUnknownColumn, name, s.Name)
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}, nil
+}
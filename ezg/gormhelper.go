@@ -1,29 +1,70 @@
 package ezg
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 	"reflect"
 )
 
 // Database helper methods use wrapper function to apply general purpose functions. Most of the functions can be
-// overridden if passed model implements called function (with variant functions instead of defining 2 methods, it uses second
-// parameter for variant, ie. instead of ShallowFindOne(gorm) and FindOne(gorm), the override is FindOne(gorm, shallow bool))
+// overridden if passed model implements override interface of the function (such as FindOverrider). Instead of defining
+// 2 methods for variant functions, the override uses parameter for variant, ie. instead of ShallowFindOne(gorm) and
+// FindOne(gorm), the override is FindOne(gorm, shallow bool).
 // If no records are found, instead of returning error record not found, a nil object will be returned. For slices,
 // instead of nil slice, empty slice is returned. Helper is bootstrapped with &model{} where it's fields will be passed
 // to gorm as WHERE clause, allowing querying like this:
 // result, err := W(&Model{UUID: "abc"}).FindOne(orm)
+// Like in gorm, fields holding zero value (0, false, "") are not part of that clause, unless they are listed with Match:
+// result, err := W(&Model{UUID: "abc", Active: false}).Match("Active").FindOne(orm)
 // Relations should be marked with function on model struct:
 // RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB)
 // or, for only single relation
 // RequiresPreload() (string, func(orm *gorm.DB) *gorm.DB)
 // where string is name of other model, and function is for things like order by. Function can be nil, and if it's not
-// used for anything useful, should be nil. Not including RequiresPreload on model that does require preloading will
-// break that relation.
+// used for anything useful, should be nil. Models without RequiresPreload have every relation gorm recognizes
+// (has-one, belongs-to, has-many, many2many, polymorphic, also inside embedded structs) preloaded, recursively, except
+// fields tagged ezg:"no-preload". Fields that are not relations (gorm:"-", serializer, []byte, ...) are never
+// preloaded. Relations closing a cycle (Category.Children, Post.Author of Author.Posts) are not preloaded, unless
+// tagged ezg:"preload-depth=N" to be followed N levels deep.
+// Preloads found this way can be configured by tag of the relation field, instead of implementing RequiresPreload:
+// Posts []Post `ezg:"preload,where=published = true,order=created_at desc,limit=10"`
+// where limit applies to each parent record (10 latest posts of every author), not to the preload query as a whole.
+// Preloads of the model can be narrowed or extended per call with With, WithLimit, Without and PreloadOnly:
+// author, err := W(&Author{}).Without("Posts.Images").FindByID(orm, id)
+// To-one relations can be preloaded by JOIN in the main query instead of separate query, with Joined or ezg:"join" tag.
+// Integer fields tagged with ezg:"count=Posts" (and gorm:"-") are filled by non-shallow finders with number of records
+// of the association, counted with one query per association, see CountAssociation.
+// Inconsistent preload definitions make non-shallow finders fail with *PreloadError, use Validate to detect them early.
+// Every error returned by the helper is *QueryError naming the failed operation, wrapping sentinels (ErrNotGormModel,
+// ErrInvalidPreload, ErrUnknownColumn, ...) as well as gorm and driver errors, so it can be inspected with errors.Is
+// and errors.As. With Strict, single record finders return ErrNotFound instead of nil model.
+// Many models are inserted in batches with InsertMany, inserted or updated on conflict with Upsert and UpsertMany.
+// Records matching the conditions finders use are updated and deleted in bulk with UpdateWhere and DeleteWhere.
+// Several operations run atomically with InTx, which retries the transaction when it conflicts with concurrent one.
+// Helper can be bound to a context with WithContext, in which case every query it issues (including preloads and
+// the gorm handle passed to overrides) carries that context, so cancellation and deadlines reach the database:
+// result, err := W(&Model{UUID: "abc"}).WithContext(ctx).FindOne(orm)
 
 // Q represents a generalized struct wrapper that is used for CRUD operations on any gorm.Model.
-type Q[t any] struct{ obj *t }
+type Q[t any] struct {
+	obj      *t
+	ctx      context.Context
+	filters  []Filter
+	match    []string
+	orders   []Order
+	window   bool
+	strict   bool
+	with     []string
+	without  []string
+	only     []string
+	joined   []string
+	limits   []preloadLimit
+	allowAll bool
+}
 
 // M is a short form for Model. It returns the underlying model.
 func (q Q[t]) M() *t {
@@ -35,50 +76,211 @@ func W[t any](obj *t) Q[t] {
 	return Q[t]{obj: obj}
 }
 
+// WithContext returns a copy of the wrapper bound to ctx. Every operation of the returned wrapper runs gorm with
+// db.WithContext(ctx), which is also the handle received by preload functions and model overrides.
+func (q Q[t]) WithContext(ctx context.Context) Q[t] {
+	q.ctx = ctx
+	return q
+}
+
+// Where returns a copy of the wrapper with filters added to the conditions of every finder (including the Sql
+// variants, where they are joined with the custom SQL by AND). Multiple filters are joined by AND.
+func (q Q[t]) Where(filters ...Filter) Q[t] {
+	q.filters = append(append(make([]Filter, 0, len(q.filters)+len(filters)), q.filters...), filters...)
+	return q
+}
+
+// Match returns a copy of the wrapper where listed fields (field names or column names) of the wrapped model are
+// matched by struct-based finders and Count even when they hold zero value, so that
+// W(&Task{Done: false}).Match("Done").Find(orm)
+// returns only tasks that are not done, instead of every task. Non-zero fields are matched as usual.
+func (q Q[t]) Match(fields ...string) Q[t] {
+	q.match = append(append(make([]string, 0, len(q.match)+len(fields)), q.match...), fields...)
+	return q
+}
+
+// OrderBy returns a copy of the wrapper with orders appended to the ordering of finders. Without any order, slices
+// are ordered by primary key. FindOne variants use the order to pick the first record.
+func (q Q[t]) OrderBy(orders ...Order) Q[t] {
+	q.orders = append(append(make([]Order, 0, len(q.orders)+len(orders)), q.orders...), orders...)
+	return q
+}
+
+// Strict returns a copy of the wrapper whose single record finders (FindOne, FindOneSql, FindByID, Join and their
+// Shallow variants) return ErrNotFound instead of nil model when there is no matching record, and whose Delete
+// returns ErrNotFound when no record was deleted.
+func (q Q[t]) Strict() Q[t] {
+	q.strict = true
+	return q
+}
+
+// session applies the bound context (if any) to db.
+func (q Q[t]) session(db *gorm.DB) *gorm.DB {
+	if q.ctx == nil {
+		return db
+	}
+	return db.WithContext(q.ctx)
+}
+
+// schema returns parsed gorm schema of the wrapped model.
+func (q Q[t]) schema(db *gorm.DB) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(q.obj); err != nil {
+		return nil, fmt.Errorf("LOGIC ERROR: %w: %w", ErrNotGormModel, err)
+	}
+	return stmt.Schema, nil
+}
+
+// conditions applies the wrapped model as struct condition (honouring fields registered with Match) and filters
+// registered with Where to qry.
+func (q Q[t]) conditions(qry *gorm.DB) (*gorm.DB, error) {
+	if len(q.match) == 0 {
+		return q.filtered(qry.Where(q.obj))
+	}
+	s, err := q.schema(qry)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool, len(q.match))
+	for _, name := range q.match {
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w %q in model %s", ErrUnknownColumn, name, s.Name)
+		}
+		matched[field.DBName] = true
+	}
+
+	rv := reflect.ValueOf(q.obj).Elem()
+	exprs := make([]clause.Expression, 0)
+	for _, field := range s.Fields {
+		if field.DBName == "" || !field.Readable {
+			continue
+		}
+		if v, zero := field.ValueOf(qry.Statement.Context, rv); !zero || matched[field.DBName] {
+			exprs = append(exprs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v})
+		}
+	}
+	if len(exprs) > 0 {
+		qry = qry.Where(clause.And(exprs...))
+	}
+	return q.filtered(qry)
+}
+
+// ordered applies orders registered with OrderBy to qry, falling back to primary key order.
+func (q Q[t]) ordered(qry *gorm.DB, reverse bool) (*gorm.DB, error) {
+	s, err := q.schema(qry)
+	if err != nil {
+		return nil, err
+	}
+	orders := q.orders
+	if len(orders) == 0 {
+		for _, field := range s.PrimaryFields {
+			orders = append(orders, Asc(field.DBName))
+		}
+	}
+	if len(orders) == 0 {
+		return qry, nil
+	}
+	by, err := orderBy(s, orders, reverse)
+	if err != nil {
+		return nil, err
+	}
+	return qry.Order(by), nil
+}
+
+// first retrieves the first record into the wrapped model, ordered by orders registered with OrderBy or by
+// primary key. It returns the executed statement, which is nil if the query failed before reaching the database.
+func (q Q[t]) first(qry *gorm.DB) (*gorm.DB, error) {
+	if len(q.orders) == 0 {
+		tx := qry.First(q.obj)
+		return tx, tx.Error
+	}
+	qry, err := q.ordered(qry, false)
+	if err != nil {
+		return nil, err
+	}
+	tx := qry.Take(q.obj)
+	return tx, tx.Error
+}
+
+// filtered applies filters registered with Where to qry.
+func (q Q[t]) filtered(qry *gorm.DB) (*gorm.DB, error) {
+	if len(q.filters) == 0 {
+		return qry, nil
+	}
+	s, err := q.schema(qry)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := And(q.filters...).build(s)
+	if err != nil || expr == nil {
+		return qry, err
+	}
+	return qry.Where(expr), nil
+}
+
 // Insert inserts the underlying model object into the database using GORM.
 // If the model implements a custom Insert method, it will be used instead.
 func (q Q[t]) Insert(db *gorm.DB) error {
-	if o, ok := interface{}(q.obj).(interface{ Insert(db *gorm.DB) error }); ok {
-		return o.Insert(db)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(InsertOverrider); ok {
+		return q.fail("Insert", nil, o.Insert(db))
 	}
 
-	return db.Create(q.obj).Error
+	tx := db.Create(q.obj)
+	return q.fail("Insert", tx, tx.Error)
 }
 
 // Update updates the underlying model object in the database using GORM.
 // If the model implements a custom Update method, it will be used instead.
 func (q Q[t]) Update(db *gorm.DB) error {
-	if o, ok := interface{}(q.obj).(interface{ Update(db *gorm.DB) error }); ok {
-		return o.Update(db)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(UpdateOverrider); ok {
+		return q.fail("Update", nil, o.Update(db))
 	}
 
-	return db.Save(q.obj).Error
+	tx := db.Save(q.obj)
+	return q.fail("Update", tx, tx.Error)
 }
 
-// Delete deletes the underlying model object from the database using GORM.
+// Delete deletes the underlying model object from the database using GORM, identified by its primary key (which
+// may be of any type, or composite).
 // If the model implements a custom Delete method, it will be used instead.
-// If the model does not use gorm.Model while not implementing custom model method, it will return an error.
+// If the model has no primary key, or primary key is not set, while not implementing custom model method, it will
+// return ErrNotGormModel. In strict mode, deleting record that does not exist returns ErrNotFound.
 func (q Q[t]) Delete(db *gorm.DB) error {
-	if o, ok := interface{}(q.obj).(interface{ Delete(db *gorm.DB) error }); ok {
-		return o.Delete(db)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(DeleteOverrider); ok {
+		return q.fail("Delete", nil, o.Delete(db))
 	}
 
-	metaValue := reflect.ValueOf(q.obj).Elem()
-	field := metaValue.FieldByName("Model")
-	if field == (reflect.Value{}) {
-		return errors.New("LOGIC ERROR: model is not gorm.Model. Implement override function")
+	s, err := q.schema(db)
+	if err != nil {
+		return q.fail("Delete", nil, err)
+	}
+	if len(s.PrimaryFields) == 0 {
+		return q.fail("Delete", nil, fmt.Errorf("LOGIC ERROR: %w: model %s has no primary key. Implement override function", ErrNotGormModel, s.Name))
+	}
+	rv := reflect.ValueOf(q.obj).Elem()
+	set := false
+	for _, field := range s.PrimaryFields {
+		if _, zero := field.ValueOf(db.Statement.Context, rv); !zero {
+			set = true
+		}
 	}
-	field = field.FieldByName("ID")
-	if field == (reflect.Value{}) || !field.CanUint() {
-		return errors.New("LOGIC ERROR: model is not gorm.Model. Implement override function")
+	if !set {
+		return q.fail("Delete", nil, fmt.Errorf("LOGIC ERROR: %w: primary key of model %s is not set", ErrNotGormModel, s.Name))
 	}
-	value := field.Uint()
-	return db.Model(q.obj).Delete("id", value).Error
+	tx := db.Delete(q.obj)
+	if tx.Error == nil && tx.RowsAffected == 0 && q.strict {
+		return q.fail("Delete", tx, ErrNotFound)
+	}
+	return q.fail("Delete", tx, tx.Error)
 }
 
 // FindOne retrieves a single instance of the underlying model from the database using GORM.
 // If the model implements a custom FindOne method, it will be used instead.
-// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
 func (q Q[t]) FindOne(db *gorm.DB) (*t, error) {
 	return q.findOne(db, false)
 }
@@ -86,33 +288,33 @@ func (q Q[t]) FindOne(db *gorm.DB) (*t, error) {
 // ShallowFindOne retrieves a single instance of the underlying model from the database using GORM,
 // without preloading any associations.
 // If the model implements a custom FindOne method, it will be used instead.
-// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
 func (q Q[t]) ShallowFindOne(db *gorm.DB) (*t, error) {
 	return q.findOne(db, true)
 }
 
 func (q Q[t]) findOne(db *gorm.DB, shallow bool) (*t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		FindOne(db *gorm.DB, shallow bool) (*t, error)
-	}); ok {
-		return o.FindOne(db, shallow)
+	op := opName("FindOne", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindOneOverrider[t]); ok {
+		return q.found(op, nil)(o.FindOne(db, shallow))
 	}
 
-	err := q.preload(db.Where(q.obj), shallow).First(q.obj).Error
-
-	if err == gorm.ErrRecordNotFound {
-		return nil, nil
-	}
+	qry, err := q.conditions(db)
 	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
+		return nil, q.fail(op, nil, err)
 	}
-	return q.obj, err
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	tx, err := q.first(qry)
+	return q.found(op, tx)(q.obj, err)
 }
 
 // FindOneSql retrieves a single instance of the underlying model from the database using GORM,
 // using a custom SQL query.
 // If the model implements a custom FindOneSql method, it will be used instead.
-// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
 func (q Q[t]) FindOneSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (*t, error) {
 	return q.findOneSql(db, false, sql, sqlArgs...)
 }
@@ -120,27 +322,136 @@ func (q Q[t]) FindOneSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (*t, e
 // ShallowFindOneSql retrieves a single instance of the underlying model from the database using GORM,
 // using a custom SQL query and without preloading any associations.
 // If the model implements a custom FindOneSql method, it will be used instead.
-// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
 func (q Q[t]) ShallowFindOneSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (*t, error) {
 	return q.findOneSql(db, true, sql, sqlArgs...)
 }
 
 func (q Q[t]) findOneSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) (*t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		FindOneSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (*t, error)
-	}); ok {
-		return o.FindOneSql(db, sql, sqlArgs...)
+	op := opName("FindOneSql", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindOneSqlOverrider[t]); ok {
+		return q.found(op, nil)(o.FindOneSql(db, shallow, sql, sqlArgs...))
+	}
+
+	qry, err := q.filtered(db.Model(q.obj).Where(sql, sqlArgs...))
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
+	tx, err := q.first(qry)
+	return q.found(op, tx)(q.obj, err)
+}
 
-	err := q.preload(db.Model(q.obj).Where(sql, sqlArgs...), shallow).First(q.obj).Error
+// FindByID retrieves a single instance of the underlying model by its primary key, given as one value per primary
+// key field (in order of declaration for composite keys). Fields of the wrapped model are not used as conditions,
+// filters registered with Where are.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
+func (q Q[t]) FindByID(db *gorm.DB, pk ...interface{}) (*t, error) {
+	return q.findByID(db, false, pk)
+}
 
-	if err == gorm.ErrRecordNotFound {
-		return nil, nil
+// ShallowFindByID is FindByID without preloading any associations.
+func (q Q[t]) ShallowFindByID(db *gorm.DB, pk ...interface{}) (*t, error) {
+	return q.findByID(db, true, pk)
+}
+
+func (q Q[t]) findByID(db *gorm.DB, shallow bool, pk []interface{}) (*t, error) {
+	op := opName("FindByID", shallow)
+	db = q.session(db)
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, q.fail(op, nil, fmt.Errorf("LOGIC ERROR: %w: model %s has no primary key", ErrNotGormModel, s.Name))
+	}
+	if len(pk) != len(s.PrimaryFields) {
+		return nil, q.fail(op, nil, fmt.Errorf("LOGIC ERROR: model %s has %d primary key fields, got %d values", s.Name, len(s.PrimaryFields), len(pk)))
+	}
+	key := interface{}(pk)
+	if len(pk) == 1 {
+		key = pk[0]
+	}
+	qry, err := q.filtered(db.Where(primaryKeyIn(s, []interface{}{key})))
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+
+	out := new(t)
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	tx := qry.Take(out)
+	return q.found(op, tx)(out, tx.Error)
+}
+
+// FindByIDs retrieves instances of the underlying model with given primary keys. For models with single primary key
+// field, every pk is a value of the key, for composite keys, every pk is []interface{} holding value of every primary
+// key field (in order of declaration). Fields of the wrapped model are not used as conditions, filters registered
+// with Where are. Models are ordered like with Find.
+// Instead of using gorm.ErrRecordNotFound it will return empty slice and nil error.
+func (q Q[t]) FindByIDs(db *gorm.DB, pks ...interface{}) ([]t, error) {
+	return q.findByIDs(db, false, pks)
+}
+
+// ShallowFindByIDs is FindByIDs without preloading any associations.
+func (q Q[t]) ShallowFindByIDs(db *gorm.DB, pks ...interface{}) ([]t, error) {
+	return q.findByIDs(db, true, pks)
+}
+
+func (q Q[t]) findByIDs(db *gorm.DB, shallow bool, pks []interface{}) ([]t, error) {
+	op := opName("FindByIDs", shallow)
+	db = q.session(db)
+	out := make([]t, 0)
+	if len(pks) == 0 {
+		return out, nil
+	}
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, q.fail(op, nil, fmt.Errorf("LOGIC ERROR: %w: model %s has no primary key", ErrNotGormModel, s.Name))
+	}
+	if len(s.PrimaryFields) > 1 {
+		for i := range pks {
+			if key, ok := pks[i].([]interface{}); !ok || len(key) != len(s.PrimaryFields) {
+				return nil, q.fail(op, nil, fmt.Errorf("LOGIC ERROR: model %s has composite primary key, every pk must be []interface{} of %d values", s.Name, len(s.PrimaryFields)))
+			}
+		}
 	}
+	qry, err := q.filtered(db.Where(primaryKeyIn(s, pks)))
 	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
+		return nil, q.fail(op, nil, err)
+	}
+	if qry, err = q.ordered(qry, false); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
-	return q.obj, err
+
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	return q.all(op, qry.Find(&out), out)
+}
+
+// primaryKeyIn builds condition matching primary key against keys, which are []interface{} for composite keys.
+func primaryKeyIn(s *schema.Schema, keys []interface{}) clause.Expression {
+	return fieldsIn(s.PrimaryFields, keys)
+}
+
+// fieldsIn builds condition matching columns of fields against keys, which are []interface{} for multiple fields.
+func fieldsIn(fields []*schema.Field, keys []interface{}) clause.Expression {
+	if len(fields) == 1 {
+		return clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: fields[0].DBName}, Values: keys}
+	}
+	cols := make([]clause.Column, len(fields))
+	for i, field := range fields {
+		cols[i] = clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	}
+	return clause.IN{Column: cols, Values: keys}
 }
 
 // Find retrieves all instances of the underlying model from the database using GORM.
@@ -159,19 +470,25 @@ func (q Q[t]) ShallowFind(db *gorm.DB) ([]t, error) {
 }
 
 func (q Q[t]) find(db *gorm.DB, shallow bool) ([]t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		Find(db *gorm.DB) ([]t, error)
-	}); ok {
-		return o.Find(db)
+	op := opName("Find", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindOverrider[t]); ok {
+		out, err := o.Find(db, shallow)
+		return out, q.fail(op, nil, err)
 	}
 
+	qry, err := q.conditions(db)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	if qry, err = q.ordered(qry, false); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	out := make([]t, 0)
-	err := q.preload(db.Where(q.obj), shallow).Order("id ASC").Find(&out).Error
-
-	if err == gorm.ErrRecordNotFound {
-		return make([]t, 0), nil
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
-	return out, err
+	return q.all(op, qry.Find(&out), out)
 }
 
 // FindSql retrieves all instances of the underlying model from the database using GORM,
@@ -184,26 +501,22 @@ func (q Q[t]) FindSql(db *gorm.DB, sql string, sqlArgs ...interface{}) ([]t, err
 
 // Join retrieves a single instance of the underlying model from the database using GORM,
 // with a join on another table using a custom condition.
-// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error.
+// Instead of using gorm.ErrRecordNotFound it will return nil model and nil error (ErrNotFound in strict mode).
 func (q Q[t]) Join(db *gorm.DB, table, condition string) (*t, error) {
 	return q.join(db, table, condition)
 }
 
 func (q Q[t]) join(db *gorm.DB, table, condition string) (*t, error) {
-	err := q.preload(
-		db.Model(q.obj).Joins(fmt.Sprintf("INNER JOIN %s ON %s", table, condition)),
-		false,
-	).First(q.obj).Error
-
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, nil
-	}
-
+	db = q.session(db)
+	qry, err := q.filtered(db.Model(q.obj).Joins(fmt.Sprintf("INNER JOIN %s ON %s", table, condition)))
 	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
+		return nil, q.fail("Join", nil, err)
 	}
-
-	return q.obj, err
+	if qry, err = q.preload(qry, false); err != nil {
+		return nil, q.fail("Join", nil, err)
+	}
+	tx, err := q.first(qry)
+	return q.found("Join", tx)(q.obj, err)
 }
 
 // ShallowFindSql retrieves all instances of the underlying model from the database using GORM,
@@ -215,22 +528,29 @@ func (q Q[t]) ShallowFindSql(db *gorm.DB, sql string, sqlArgs ...interface{}) ([
 }
 
 func (q Q[t]) findSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) ([]t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		FindSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) ([]t, error)
-	}); ok {
-		return o.FindSql(db, shallow, sql, sqlArgs...)
+	op := opName("FindSql", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindSqlOverrider[t]); ok {
+		out, err := o.FindSql(db, shallow, sql, sqlArgs...)
+		return out, q.fail(op, nil, err)
 	}
 
+	qry, err := q.filtered(db.Model(q.obj).Where(sql, sqlArgs...))
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	if qry, err = q.ordered(qry, false); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	out := make([]t, 0)
-	err := q.preload(db.Model(q.obj).Where(sql, sqlArgs...), shallow).Order("id ASC").Find(&out).Error
-
-	if err == gorm.ErrRecordNotFound {
-		return make([]t, 0), nil
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
-	return out, err
+	return q.all(op, qry.Find(&out), out)
 }
 
 // FindPaginated retrieves a slice of models from the database with pagination parameters (limit and offset).
+// reverseOrder reverses the ordering set with OrderBy (primary key by default).
 // If the model implements a custom FindPaginated method, it will be used instead.
 // Instead of using gorm.ErrRecordNotFound it will return empty slice and nil error.
 func (q Q[t]) FindPaginated(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool) ([]t, error) {
@@ -244,35 +564,35 @@ func (q Q[t]) ShallowFindPaginated(db *gorm.DB, offset *uint64, limit *uint64, r
 	return q.findPaginated(db, offset, limit, reverseOrder, true)
 }
 func (q Q[t]) findPaginated(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder, shallow bool) ([]t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		FindPaginated(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, shallow bool) ([]t, error)
-	}); ok {
-		return o.FindPaginated(db, offset, limit, reverseOrder, shallow)
+	op := opName("FindPaginated", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindPaginatedOverrider[t]); ok {
+		out, err := o.FindPaginated(db, offset, limit, reverseOrder, shallow)
+		return out, q.fail(op, nil, err)
 	}
 
+	qry, err := q.conditions(db)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	out := make([]t, 0)
-	qry := q.preload(db.Where(q.obj), shallow)
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	if offset != nil {
 		qry = qry.Offset(int(*offset))
 	}
 	if limit != nil {
 		qry = qry.Limit(int(*limit))
 	}
-	orderStr := "id ASC"
-	if reverseOrder {
-		orderStr = "id DESC"
+	if qry, err = q.ordered(qry, reverseOrder); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
-	err := qry.Order(orderStr).Find(&out).Error
-	if err == gorm.ErrRecordNotFound {
-		return make([]t, 0), nil
-	}
-	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
-	}
-	return out, err
+	return q.all(op, qry.Find(&out), out)
 }
 
 // FindPaginatedSql retrieves a slice of models from the database with pagination, optional reverse ordering, and with custom WHERE SQL.
+// reverseOrder reverses the ordering set with OrderBy (primary key by default).
 // If the model implements a custom FindPaginatedSql method, it will be used instead.
 // Instead of using gorm.ErrRecordNotFound it will return empty slice and nil error.
 func (q Q[t]) FindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, sql string, sqlArgs ...interface{}) ([]t, error) {
@@ -286,122 +606,183 @@ func (q Q[t]) ShallowFindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64
 	return q.findPaginatedSql(db, offset, limit, reverseOrder, true, sql, sqlArgs...)
 }
 func (q Q[t]) findPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder, shallow bool, sql string, sqlArgs ...interface{}) ([]t, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		FindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, sql string, sqlArgs ...interface{}) ([]t, error)
-	}); ok {
-		return o.FindPaginatedSql(db, offset, limit, reverseOrder, sql, sqlArgs...)
+	op := opName("FindPaginatedSql", shallow)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(FindPaginatedSqlOverrider[t]); ok {
+		out, err := o.FindPaginatedSql(db, offset, limit, reverseOrder, shallow, sql, sqlArgs...)
+		return out, q.fail(op, nil, err)
 	}
 
+	qry, err := q.filtered(db.Model(q.obj).Where(sql, sqlArgs...))
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	out := make([]t, 0)
-	qry := q.preload(db.Model(q.obj).Where(sql, sqlArgs...), shallow)
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return nil, q.fail(op, nil, err)
+	}
 	if offset != nil {
 		qry = qry.Offset(int(*offset))
 	}
 	if limit != nil {
 		qry = qry.Limit(int(*limit))
 	}
-	orderStr := "id ASC"
-	if reverseOrder {
-		orderStr = "id DESC"
+	if qry, err = q.ordered(qry, reverseOrder); err != nil {
+		return nil, q.fail(op, nil, err)
 	}
-	err := qry.Order(orderStr).Find(&out).Error
-	if err == gorm.ErrRecordNotFound {
-		return make([]t, 0), nil
-	}
-	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
-	}
-
-	return out, err
+	return q.all(op, qry.Find(&out), out)
 }
 
 // CountSql counts the number of rows in the database that match the custom SQL query and arguments.
 // If the model implements a custom CountSql method, it will be used instead.
 func (q Q[t]) CountSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (uint64, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		CountSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (uint64, error)
-	}); ok {
-		return o.CountSql(db, sql, sqlArgs...)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(CountSqlOverrider); ok {
+		count, err := o.CountSql(db, sql, sqlArgs...)
+		return count, q.fail("CountSql", nil, err)
 	}
-	count := int64(0)
-	err := db.Model(&q.obj).Where(sql, sqlArgs...).Count(&count).Error
-	if err == gorm.ErrRecordNotFound {
-		return 0, nil
+	qry, err := q.filtered(db.Model(&q.obj).Where(sql, sqlArgs...))
+	if err != nil {
+		return 0, q.fail("CountSql", nil, err)
 	}
-
-	return uint64(count), err
+	return q.count("CountSql", qry)
 }
 
 // Count counts the number of rows in the database that match the model.
 // If the model implements a custom Count method, it will be used instead.
 func (q Q[t]) Count(db *gorm.DB) (uint64, error) {
-	if o, ok := interface{}(q.obj).(interface {
-		Count(db *gorm.DB) (uint64, error)
-	}); ok {
-		return o.Count(db)
+	db = q.session(db)
+	if o, ok := interface{}(q.obj).(CountOverrider); ok {
+		count, err := o.Count(db)
+		return count, q.fail("Count", nil, err)
 	}
 
+	qry, err := q.conditions(db.Model(q.obj))
+	if err != nil {
+		return 0, q.fail("Count", nil, err)
+	}
+	return q.count("Count", qry)
+}
+
+func (q Q[t]) count(op string, qry *gorm.DB) (uint64, error) {
 	count := int64(0)
-	err := db.Model(q.obj).Where(q.obj).Count(&count).Error
-	if err == gorm.ErrRecordNotFound {
+	tx := qry.Count(&count)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		return 0, nil
 	}
-	if err != nil {
-		err = fmt.Errorf("failed to read database: %w", err)
+	return uint64(count), q.fail(op, tx, tx.Error)
+}
+
+// all finishes slice finders, turning gorm.ErrRecordNotFound into empty slice.
+func (q Q[t]) all(op string, tx *gorm.DB, out []t) ([]t, error) {
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return make([]t, 0), nil
+	}
+	if tx.Error != nil {
+		return out, q.fail(op, tx, tx.Error)
+	}
+	if err := counted(tx, reflect.ValueOf(out)); err != nil {
+		return nil, q.fail(op, tx, err)
 	}
-	return uint64(count), err
+	return out, nil
 }
 
-func (q Q[t]) preload(qry *gorm.DB, shallow bool) *gorm.DB {
-	if shallow {
-		return qry
-	}
-	if o, ok := interface{}(q.obj).(interface {
-		RequiresPreload() (string, func(orm *gorm.DB) *gorm.DB)
-	}); ok {
-		a, b := o.RequiresPreload()
-		if b == nil {
-			return qry.Preload(a)
-		}
-		return qry.Preload(a, b)
-	} else if o, ok := interface{}(q.obj).(interface { // support for multi table preload
-		RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB)
-	}); !ok {
-		a := autoPreloads(q.obj)
-		for i := range a {
-			qry = qry.Preload(a[i])
-		}
-	} else {
-		a, b := o.RequiresPreload()
-		if b == nil || len(b) == 0 {
-			for i := range a {
-				qry = qry.Preload(a[i])
+// found returns function finishing single record finders: it turns gorm.ErrRecordNotFound (or nil model returned by
+// override) into nil model, or ErrNotFound in strict mode.
+func (q Q[t]) found(op string, tx *gorm.DB) func(*t, error) (*t, error) {
+	return func(obj *t, err error) (*t, error) {
+		if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && obj == nil {
+			if q.strict {
+				return nil, q.fail(op, tx, ErrNotFound)
 			}
-			return qry
+			return nil, nil
 		}
-		if len(a) != len(b) {
-			name := reflect.ValueOf(q.obj).Type().Name()
-			//           This is a logic error which will be obvious if it occurs - every read
-			//           of the affected table will fail. As the RequiresPreload interface should be constant for a model,
-			//           this error points to an inconsistency in the model's definition. Hence, it is justified to halt
-			//           execution (panic) so this logic error can be detected and fixed during the development phase.
-			//           Specifically, when a model declares multi-preload, the length of gorm functions (preload
-			//           conditions or modifications) should be either 0 (meaning no specific conditions for all
-			//           preloaded tables) or equal to the length of preloaded tables (meaning each preload has a
-			//           corresponding condition or modification, even if it's nil - which is valid usage).
-			panic(fmt.Sprintf("LOGIC ERROR: model %s declares multi-preload but does not define consistent "+
-				"preload definition, length of gorm functions must be either 0 or equal to length of preloaded tables, "+
-				"instead got len(tables) = %d, len(gormFuncs) = %d. Gorm function is allways allowable to be nil for"+
-				" selective usage", name, len(a), len(b)))
+		if err != nil {
+			return obj, q.fail(op, tx, err)
 		}
-		for i := range a {
-			if b[i] == nil {
-				qry = qry.Preload(a[i])
-			} else {
-				qry = qry.Preload(a[i], b[i])
-			}
+		if err = counted(tx, reflect.ValueOf(obj)); err != nil {
+			return nil, q.fail(op, tx, err)
 		}
+		return obj, nil
 	}
+}
 
-	return qry
+// fail wraps err of operation op into *QueryError, along with SQL of tx if it reached the database. It returns nil for
+// nil err. Errors already wrapped by other operation (such as Count called by FindPage) are re-labeled with op.
+func (q Q[t]) fail(op string, tx *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+	if qe, ok := err.(*QueryError); ok {
+		return &QueryError{Op: op, Model: qe.Model, SQL: qe.SQL, Err: qe.Err}
+	}
+	qe := &QueryError{Op: op, Model: modelName(q.obj), Err: constraintError(err)}
+	if tx != nil {
+		qe.SQL = statementSQL(tx)
+	}
+	return qe
+}
+
+// statementSQL returns SQL of executed statement. Gorm discards it after execution, so it is built again from clauses
+// of the statement, which are kept.
+func statementSQL(tx *gorm.DB) string {
+	stmt := tx.Statement
+	if stmt == nil || len(stmt.Clauses) == 0 {
+		return ""
+	}
+	if stmt.SQL.Len() > 0 {
+		return stmt.SQL.String()
+	}
+	// the statement is finished, so it can be reused for building
+	for _, clauses := range [][]string{
+		{"INSERT", "VALUES", "ON CONFLICT", "RETURNING"},
+		{"UPDATE", "SET", "WHERE", "RETURNING"},
+		{"DELETE", "FROM", "WHERE", "RETURNING"},
+	} {
+		if _, ok := stmt.Clauses[clauses[0]]; ok {
+			stmt.Build(clauses...)
+			return stmt.SQL.String()
+		}
+	}
+	stmt.Build("SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "LIMIT", "FOR")
+	return stmt.SQL.String()
+}
+
+// opName returns name of the operation, prefixed with Shallow for shallow variants.
+func opName(name string, shallow bool) string {
+	if shallow {
+		return "Shallow" + name
+	}
+	return name
+}
+
+func (q Q[t]) preload(qry *gorm.DB, shallow bool) (*gorm.DB, error) {
+	return q.preloadAs(qry, shallow, "")
+}
+
+// preloadAs preloads relations of the model, with relation names prefixed by prefix. It's used when the model is
+// embedded in other struct.
+func (q Q[t]) preloadAs(qry *gorm.DB, shallow bool, prefix string) (*gorm.DB, error) {
+	if shallow {
+		return qry, nil
+	}
+	specs, err := q.selectedPreloads(qry)
+	if err != nil {
+		return nil, err
+	}
+	if qry, err = q.withCounts(qry); err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		switch {
+		case spec.join && prefix == "":
+			// gorm can't join relations of embedded struct, which is how the model is preloaded with prefix
+			qry = qry.Joins(spec.name)
+		case spec.cond == nil:
+			qry = qry.Preload(prefix + spec.name)
+		default:
+			qry = qry.Preload(prefix+spec.name, spec.cond)
+		}
+	}
+	return qry, nil
 }
@@ -0,0 +1,310 @@
+package ezg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// maxKeysetLimit is the largest page size of keyset finders, larger limits are lowered to it.
+const maxKeysetLimit = 10000
+
+var cursorKey atomic.Value
+
+func init() {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate cursor key: %v", err))
+	}
+	cursorKey.Store(key)
+}
+
+// SetCursorKey sets the secret used to sign keyset pagination cursors. By default, a random key is generated on
+// startup, which means cursors are only valid within single process. Services running more than one instance should
+// set a shared key during initialization.
+func SetCursorKey(key []byte) {
+	cursorKey.Store(append([]byte(nil), key...))
+}
+
+// KeysetPage is a page of records retrieved with keyset (cursor) pagination.
+type KeysetPage[t any] struct {
+	Items []t
+	// Next is the cursor of the page following Items, empty if there are no further records.
+	Next string
+	// Prev is the cursor of the page preceding Items, empty on the first page.
+	Prev string
+}
+
+type cursorPayload struct {
+	Backward bool   `json:"b,omitempty"`
+	Order    string `json:"o"`
+	// Filter is hash of the conditions of the query the cursor was issued for, see keysetFilter.
+	Filter string            `json:"f,omitempty"`
+	Keys   []json.RawMessage `json:"k"`
+}
+
+// FindKeyset retrieves a page of at most limit models that match the wrapped model (and filters), continuing after
+// cursor, which is either empty for the first page or Next / Prev of previously returned page. Records are ordered by
+// OrderBy, with primary key appended as a tie-breaker, and columns used for ordering must not hold NULL values.
+// Limit above 10000 is lowered to it, the rest of records is on the following pages.
+// Cursors are opaque and signed (see SetCursorKey), ErrInvalidCursor is returned for cursors that fail verification or
+// were issued for query with different ordering or conditions.
+func (q Q[t]) FindKeyset(db *gorm.DB, cursor string, limit uint64) (KeysetPage[t], error) {
+	return q.findKeyset("FindKeyset", db, cursor, limit, false, q.conditions)
+}
+
+// ShallowFindKeyset is FindKeyset without preloading any associations.
+func (q Q[t]) ShallowFindKeyset(db *gorm.DB, cursor string, limit uint64) (KeysetPage[t], error) {
+	return q.findKeyset("FindKeyset", db, cursor, limit, true, q.conditions)
+}
+
+// FindKeysetSql is FindKeyset with custom WHERE SQL instead of the wrapped model.
+func (q Q[t]) FindKeysetSql(db *gorm.DB, cursor string, limit uint64, sql string, sqlArgs ...interface{}) (KeysetPage[t], error) {
+	return q.findKeyset("FindKeysetSql", db, cursor, limit, false, func(qry *gorm.DB) (*gorm.DB, error) {
+		return q.filtered(qry.Model(q.obj).Where(sql, sqlArgs...))
+	})
+}
+
+// ShallowFindKeysetSql is FindKeysetSql without preloading any associations.
+func (q Q[t]) ShallowFindKeysetSql(db *gorm.DB, cursor string, limit uint64, sql string, sqlArgs ...interface{}) (KeysetPage[t], error) {
+	return q.findKeyset("FindKeysetSql", db, cursor, limit, true, func(qry *gorm.DB) (*gorm.DB, error) {
+		return q.filtered(qry.Model(q.obj).Where(sql, sqlArgs...))
+	})
+}
+
+func (q Q[t]) findKeyset(op string, db *gorm.DB, cursor string, limit uint64, shallow bool, where func(*gorm.DB) (*gorm.DB, error)) (KeysetPage[t], error) {
+	op = opName(op, shallow)
+	db = q.session(db)
+	page := KeysetPage[t]{Items: make([]t, 0)}
+	if limit == 0 {
+		return page, nil
+	}
+	if limit > maxKeysetLimit {
+		limit = maxKeysetLimit
+	}
+	s, err := q.schema(db)
+	if err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	orders, sig, err := keysetOrders(s, q.orders)
+	if err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	qry, err := where(db)
+	if err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	filter := keysetFilter(qry, s)
+
+	backward := false
+	if cursor != "" {
+		payload, err := decodeCursor(cursor)
+		if err != nil {
+			return page, q.fail(op, nil, err)
+		}
+		if payload.Order != sig || len(payload.Keys) != len(orders) {
+			return page, q.fail(op, nil, fmt.Errorf("%w: cursor was issued for different ordering", ErrInvalidCursor))
+		}
+		if payload.Filter != filter {
+			return page, q.fail(op, nil, fmt.Errorf("%w: cursor was issued for different conditions", ErrInvalidCursor))
+		}
+		backward = payload.Backward
+		expr, err := keysetCondition(s, orders, payload.Keys, backward)
+		if err != nil {
+			return page, q.fail(op, nil, err)
+		}
+		qry = qry.Where(expr)
+	}
+
+	by, err := orderBy(s, orders, backward)
+	if err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	out := make([]t, 0)
+	if qry, err = q.preload(qry, shallow); err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	tx := qry.Order(by).Limit(int(limit) + 1).Find(&out)
+	if tx.Error != nil {
+		return page, q.fail(op, tx, tx.Error)
+	}
+	more := uint64(len(out)) > limit
+	if more {
+		out = out[:limit]
+	}
+	if err = counted(tx, reflect.ValueOf(out)); err != nil {
+		return page, q.fail(op, tx, err)
+	}
+	if backward {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	page.Items = out
+	if len(out) == 0 {
+		return page, nil
+	}
+
+	// going forward, there is previous page if we came from it, going backward, there is the page we came from
+	hasNext, hasPrev := more, cursor != ""
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if page.Next, err = encodeCursor(db, s, orders, sig, filter, &out[len(out)-1], false); err != nil {
+			return page, q.fail(op, nil, err)
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = encodeCursor(db, s, orders, sig, filter, &out[0], true); err != nil {
+			return page, q.fail(op, nil, err)
+		}
+	}
+	return page, nil
+}
+
+// keysetOrders resolves orders to column names, appending primary key columns missing in the order, so that the
+// ordering is total. It also returns signature of the ordering, which binds cursors to it.
+func keysetOrders(s *schema.Schema, orders []Order) ([]Order, string, error) {
+	out := make([]Order, 0, len(orders)+len(s.PrimaryFields))
+	seen := make(map[string]bool)
+	for _, o := range orders {
+		field := s.LookUpField(o.column)
+		if field == nil || field.DBName == "" {
+			return nil, "", fmt.Errorf("%w %q in model %s", ErrUnknownColumn, o.column, s.Name)
+		}
+		if seen[field.DBName] {
+			continue
+		}
+		seen[field.DBName] = true
+		o.column = field.DBName
+		out = append(out, o)
+	}
+	for _, field := range s.PrimaryFields {
+		if !seen[field.DBName] {
+			out = append(out, Asc(field.DBName))
+		}
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, "", fmt.Errorf("LOGIC ERROR: %w: keyset pagination requires model %s to have primary key", ErrNotGormModel, s.Name)
+	}
+
+	sig := make([]string, 0, len(out))
+	for _, o := range out {
+		if o.desc {
+			sig = append(sig, "-"+o.column)
+		} else {
+			sig = append(sig, o.column)
+		}
+	}
+	return out, strings.Join(sig, ","), nil
+}
+
+// keysetFilter returns hash of WHERE conditions of qry (the wrapped model, filters and custom SQL with its arguments),
+// which binds cursors to them, so that cursor of one query is not accepted by query with other conditions, where it
+// would select wrong page. It's empty if there are no conditions.
+func keysetFilter(qry *gorm.DB, s *schema.Schema) string {
+	where, ok := qry.Statement.Clauses["WHERE"]
+	if !ok {
+		return ""
+	}
+	stmt := &gorm.Statement{DB: qry, Table: s.Table, Schema: s, Context: qry.Statement.Context,
+		Clauses: map[string]clause.Clause{}}
+	where.Build(stmt)
+	sum := sha256.Sum256([]byte(qry.Dialector.Explain(stmt.SQL.String(), stmt.Vars...)))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+// keysetCondition builds condition selecting records strictly after (or before, if backward) given keys:
+// (a > ?) OR (a = ? AND b > ?) OR ...
+func keysetCondition(s *schema.Schema, orders []Order, keys []json.RawMessage, backward bool) (clause.Expression, error) {
+	values := make([]interface{}, len(orders))
+	for i, o := range orders {
+		field := s.LookUpField(o.column)
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(keys[i], v.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		values[i] = v.Elem().Interface()
+	}
+
+	alternatives := make([]clause.Expression, 0, len(orders))
+	for i, o := range orders {
+		terms := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: orders[j].column}, Value: values[j]})
+		}
+		col := clause.Column{Table: clause.CurrentTable, Name: o.column}
+		if o.desc != backward {
+			terms = append(terms, clause.Lt{Column: col, Value: values[i]})
+		} else {
+			terms = append(terms, clause.Gt{Column: col, Value: values[i]})
+		}
+		if len(terms) == 1 {
+			alternatives = append(alternatives, terms[0])
+		} else {
+			alternatives = append(alternatives, clause.AndConditions{Exprs: terms})
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	return clause.OrConditions{Exprs: alternatives}, nil
+}
+
+func encodeCursor(db *gorm.DB, s *schema.Schema, orders []Order, sig, filter string, item interface{}, backward bool) (string, error) {
+	rv := reflect.ValueOf(item).Elem()
+	payload := cursorPayload{Backward: backward, Order: sig, Filter: filter, Keys: make([]json.RawMessage, len(orders))}
+	for i, o := range orders {
+		v, _ := s.LookUpField(o.column).ValueOf(db.Statement.Context, rv)
+		if fv := reflect.ValueOf(v); !fv.IsValid() || fv.Kind() == reflect.Ptr && fv.IsNil() {
+			return "", fmt.Errorf("LOGIC ERROR: keyset pagination column %s of model %s holds NULL", o.column, s.Name)
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode cursor: %w", err)
+		}
+		payload.Keys[i] = raw
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw) + "." + base64.RawURLEncoding.EncodeToString(signCursor(raw)), nil
+}
+
+func decodeCursor(cursor string) (cursorPayload, error) {
+	payload := cursorPayload{}
+	data, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return payload, ErrInvalidCursor
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return payload, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, signCursor(raw)) {
+		return payload, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(raw, &payload); err != nil {
+		return payload, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+func signCursor(raw []byte) []byte {
+	mac := hmac.New(sha256.New, cursorKey.Load().([]byte))
+	mac.Write(raw)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,105 @@
+package ezg
+
+import (
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// perParentLimit limits a has-many preload query to n records per parent. Unlike Limit, which limits the preload
+// query as a whole, it numbers records of each parent with ROW_NUMBER() OVER (PARTITION BY foreign key) in a subquery
+// and keeps the first n of them:
+// SELECT * FROM (SELECT posts.*, ROW_NUMBER() OVER (PARTITION BY author_id ORDER BY ...) AS ezg_rank FROM posts
+// WHERE ...) AS posts WHERE ezg_rank <= n
+// The same query runs on every database with window functions (PostgreSQL, SQLite 3.25+, MySQL 8+).
+type perParentLimit struct {
+	partition []string
+	// order is the ORDER BY expression of the window, primary key if nil.
+	order clause.Expression
+	n     int
+}
+
+// ModifyStatement replaces builders of FROM and WHERE clauses of the statement, which wrap the query in the ranking
+// subquery. Conditions added to the statement later (such as IN of the preloaded foreign keys, or soft delete) end up
+// in the subquery, like the ones added earlier.
+func (l perParentLimit) ModifyStatement(stmt *gorm.Statement) {
+	from := stmt.Clauses["FROM"]
+	from.Builder = l.buildFrom
+	stmt.Clauses["FROM"] = from
+	where := stmt.Clauses["WHERE"]
+	where.Builder = l.buildWhere
+	stmt.Clauses["WHERE"] = where
+}
+
+// Build implements clause.Expression, the limit is built by ModifyStatement.
+func (l perParentLimit) Build(clause.Builder) {}
+
+func (l perParentLimit) buildFrom(c clause.Clause, builder clause.Builder) {
+	stmt := builder.(*gorm.Statement)
+	builder.WriteString("FROM (SELECT ")
+	builder.WriteQuoted(stmt.Table)
+	builder.WriteString(".*, ROW_NUMBER() OVER (PARTITION BY ")
+	for i, col := range l.partition {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteQuoted(clause.Column{Table: clause.CurrentTable, Name: col})
+	}
+	switch {
+	case l.order != nil:
+		builder.WriteString(" ORDER BY ")
+		l.order.Build(builder)
+	case stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil:
+		builder.WriteString(" ORDER BY ")
+		builder.WriteQuoted(clause.Column{Table: clause.CurrentTable, Name: stmt.Schema.PrioritizedPrimaryField.DBName})
+	}
+	builder.WriteString(") AS ezg_rank ")
+	c.Builder = nil
+	c.Build(builder)
+}
+
+func (l perParentLimit) buildWhere(c clause.Clause, builder clause.Builder) {
+	stmt := builder.(*gorm.Statement)
+	c.Builder = nil
+	c.Build(builder)
+	builder.WriteString(") AS ")
+	builder.WriteQuoted(stmt.Table)
+	builder.WriteString(" WHERE ezg_rank <= " + strconv.Itoa(l.n))
+}
+
+// preloadCondition returns gorm function applying where, order and per-parent limit to the preload query of rel, nil
+// if there is nothing to apply. Limit is supported only for has-many relations.
+func preloadCondition(rel *schema.Relationship, where string, order clause.Expression, limit int) (
+	func(orm *gorm.DB) *gorm.DB, error) {
+	if where == "" && order == nil && limit == 0 {
+		return nil, nil
+	}
+	var perParent *perParentLimit
+	if limit > 0 {
+		if rel.Type != schema.HasMany {
+			return nil, errors.New("limit is supported only for has-many relations, " + rel.Name + " is " +
+				string(rel.Type))
+		}
+		perParent = &perParentLimit{order: order, n: limit}
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey {
+				perParent.partition = append(perParent.partition, ref.ForeignKey.DBName)
+			}
+		}
+	}
+	return func(orm *gorm.DB) *gorm.DB {
+		if where != "" {
+			orm = orm.Where(where)
+		}
+		if order != nil {
+			orm = orm.Order(clause.OrderBy{Expression: order})
+		}
+		if perParent != nil {
+			orm = orm.Clauses(*perParent)
+		}
+		return orm
+	}, nil
+}
@@ -0,0 +1,112 @@
+package ezg
+
+import (
+	"strings"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type nullsOrder uint8
+
+const (
+	nullsDefault nullsOrder = iota
+	nullsFirst
+	nullsLast
+)
+
+// Order is a single ORDER BY term, built with Asc or Desc and attached to the wrapper with Q.OrderBy:
+// tasks, err := W(&Task{}).OrderBy(Desc("priority").NullsLast(), Asc("created_at")).Find(orm)
+// Like with Filter, column is either gorm field name or database column name and is validated against the gorm
+// schema of the wrapped model when the query runs, so sort keys coming from API users are safe to pass through.
+type Order struct {
+	column string
+	desc   bool
+	nulls  nullsOrder
+}
+
+// Asc orders by column in ascending order.
+func Asc(column string) Order {
+	return Order{column: column}
+}
+
+// Desc orders by column in descending order.
+func Desc(column string) Order {
+	return Order{column: column, desc: true}
+}
+
+// NullsFirst places NULL values before non-NULL values.
+func (o Order) NullsFirst() Order {
+	o.nulls = nullsFirst
+	return o
+}
+
+// NullsLast places NULL values after non-NULL values.
+func (o Order) NullsLast() Order {
+	o.nulls = nullsLast
+	return o
+}
+
+// ParseOrder parses comma separated list of columns, such as "-priority,created_at", where columns prefixed with
+// "-" are sorted descending and columns optionally prefixed with "+" are sorted ascending. Empty entries are skipped.
+// Columns are not validated until the query runs.
+func ParseOrder(s string) []Order {
+	out := make([]Order, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "", part == "-", part == "+":
+		case strings.HasPrefix(part, "-"):
+			out = append(out, Desc(part[1:]))
+		case strings.HasPrefix(part, "+"):
+			out = append(out, Asc(part[1:]))
+		default:
+			out = append(out, Asc(part))
+		}
+	}
+	return out
+}
+
+// reversed returns order with opposite direction, also swapping placement of NULL values.
+func (o Order) reversed() Order {
+	o.desc = !o.desc
+	switch o.nulls {
+	case nullsFirst:
+		o.nulls = nullsLast
+	case nullsLast:
+		o.nulls = nullsFirst
+	}
+	return o
+}
+
+// orderBy resolves orders against s, optionally reversing every term.
+func orderBy(s *schema.Schema, orders []Order, reverse bool) (clause.OrderBy, error) {
+	sql := strings.Builder{}
+	vars := make([]interface{}, 0, len(orders))
+	for i, o := range orders {
+		if reverse {
+			o = o.reversed()
+		}
+		col, err := column(s, o.column)
+		if err != nil {
+			return clause.OrderBy{}, err
+		}
+		if i > 0 {
+			sql.WriteString(", ")
+		}
+		sql.WriteString("?")
+		if o.desc {
+			sql.WriteString(" DESC")
+		} else {
+			sql.WriteString(" ASC")
+		}
+		switch o.nulls {
+		case nullsFirst:
+			sql.WriteString(" NULLS FIRST")
+		case nullsLast:
+			sql.WriteString(" NULLS LAST")
+		}
+		vars = append(vars, col)
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: sql.String(), Vars: vars}}, nil
+}
@@ -0,0 +1,162 @@
+package ezg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Models override operations of Q by implementing the interfaces below. Every finder override receives shallow
+// parameter, which is true when called through Shallow variant of the finder, meaning that the override must not
+// preload associations. Overrides receive db already bound to the context of the wrapper (see Q.WithContext).
+// As a method with mistyped signature silently stops being an override, assert the interface at compile time:
+// var _ ezg.FindOverrider[Task] = (*Task)(nil)
+// or run Validate, which reports methods named like an override but with different signature.
+
+// InsertOverrider overrides Q.Insert.
+type InsertOverrider interface {
+	Insert(db *gorm.DB) error
+}
+
+// UpdateOverrider overrides Q.Update.
+type UpdateOverrider interface {
+	Update(db *gorm.DB) error
+}
+
+// DeleteOverrider overrides Q.Delete.
+type DeleteOverrider interface {
+	Delete(db *gorm.DB) error
+}
+
+// FindOneOverrider overrides Q.FindOne and Q.ShallowFindOne.
+type FindOneOverrider[t any] interface {
+	FindOne(db *gorm.DB, shallow bool) (*t, error)
+}
+
+// FindOneSqlOverrider overrides Q.FindOneSql and Q.ShallowFindOneSql.
+type FindOneSqlOverrider[t any] interface {
+	FindOneSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) (*t, error)
+}
+
+// FindOverrider overrides Q.Find and Q.ShallowFind.
+type FindOverrider[t any] interface {
+	Find(db *gorm.DB, shallow bool) ([]t, error)
+}
+
+// FindSqlOverrider overrides Q.FindSql and Q.ShallowFindSql.
+type FindSqlOverrider[t any] interface {
+	FindSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) ([]t, error)
+}
+
+// FindPaginatedOverrider overrides Q.FindPaginated and Q.ShallowFindPaginated.
+type FindPaginatedOverrider[t any] interface {
+	FindPaginated(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, shallow bool) ([]t, error)
+}
+
+// FindPaginatedSqlOverrider overrides Q.FindPaginatedSql and Q.ShallowFindPaginatedSql.
+type FindPaginatedSqlOverrider[t any] interface {
+	FindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, shallow bool, sql string, sqlArgs ...interface{}) ([]t, error)
+}
+
+// CountOverrider overrides Q.Count.
+type CountOverrider interface {
+	Count(db *gorm.DB) (uint64, error)
+}
+
+// CountSqlOverrider overrides Q.CountSql.
+type CountSqlOverrider interface {
+	CountSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (uint64, error)
+}
+
+// overrideSignatures returns expected signatures of override methods of model type typ, by method name.
+func overrideSignatures(typ reflect.Type) map[string]reflect.Type {
+	var (
+		db      = reflect.TypeOf((*gorm.DB)(nil))
+		err     = reflect.TypeOf((*error)(nil)).Elem()
+		boolean = reflect.TypeOf(false)
+		str     = reflect.TypeOf("")
+		args    = reflect.TypeOf([]interface{}{})
+		u64     = reflect.TypeOf(uint64(0))
+		u64p    = reflect.TypeOf((*uint64)(nil))
+		one     = reflect.PointerTo(typ)
+		many    = reflect.SliceOf(typ)
+	)
+	fn := func(in []reflect.Type, out ...reflect.Type) reflect.Type {
+		return reflect.FuncOf(in, out, len(in) > 0 && in[len(in)-1] == args)
+	}
+	return map[string]reflect.Type{
+		"Insert":           fn([]reflect.Type{db}, err),
+		"Update":           fn([]reflect.Type{db}, err),
+		"Delete":           fn([]reflect.Type{db}, err),
+		"FindOne":          fn([]reflect.Type{db, boolean}, one, err),
+		"FindOneSql":       fn([]reflect.Type{db, boolean, str, args}, one, err),
+		"Find":             fn([]reflect.Type{db, boolean}, many, err),
+		"FindSql":          fn([]reflect.Type{db, boolean, str, args}, many, err),
+		"FindPaginated":    fn([]reflect.Type{db, u64p, u64p, boolean, boolean}, many, err),
+		"FindPaginatedSql": fn([]reflect.Type{db, u64p, u64p, boolean, boolean, str, args}, many, err),
+		"Count":            fn([]reflect.Type{db}, u64, err),
+		"CountSql":         fn([]reflect.Type{db, str, args}, u64, err),
+	}
+}
+
+// legacyOverrideSignatures returns signatures that overrides of model type typ had before every finder override
+// received shallow parameter, by method name. Such methods used to be called and now silently are not.
+func legacyOverrideSignatures(typ reflect.Type) map[string]reflect.Type {
+	var (
+		db      = reflect.TypeOf((*gorm.DB)(nil))
+		err     = reflect.TypeOf((*error)(nil)).Elem()
+		boolean = reflect.TypeOf(false)
+		str     = reflect.TypeOf("")
+		args    = reflect.TypeOf([]interface{}{})
+		u64p    = reflect.TypeOf((*uint64)(nil))
+		one     = reflect.PointerTo(typ)
+		many    = reflect.SliceOf(typ)
+	)
+	return map[string]reflect.Type{
+		"Find":             reflect.FuncOf([]reflect.Type{db}, []reflect.Type{many, err}, false),
+		"FindOneSql":       reflect.FuncOf([]reflect.Type{db, str, args}, []reflect.Type{one, err}, true),
+		"FindPaginatedSql": reflect.FuncOf([]reflect.Type{db, u64p, u64p, boolean, str, args}, []reflect.Type{many, err}, true),
+	}
+}
+
+// validateOverrides reports methods of model named like an override, whose signature does not match the override.
+// Methods with the signature of an override before shallow parameter was added are reported with hint to migrate.
+func validateOverrides(model interface{}) []error {
+	ptr := reflect.TypeOf(model)
+	signatures := overrideSignatures(ptr.Elem())
+	legacy := legacyOverrideSignatures(ptr.Elem())
+	names := make([]string, 0, len(signatures))
+	for name := range signatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0)
+	for _, name := range names {
+		want := signatures[name]
+		if m, ok := ptr.MethodByName(name); ok && m.Type.NumIn() > 0 {
+			// method type includes receiver, compare without it
+			in := make([]reflect.Type, 0, m.Type.NumIn()-1)
+			for i := 1; i < m.Type.NumIn(); i++ {
+				in = append(in, m.Type.In(i))
+			}
+			out := make([]reflect.Type, 0, m.Type.NumOut())
+			for i := 0; i < m.Type.NumOut(); i++ {
+				out = append(out, m.Type.Out(i))
+			}
+			got := reflect.FuncOf(in, out, m.Type.IsVariadic())
+			switch {
+			case got == want:
+			case got == legacy[name]:
+				errs = append(errs, fmt.Errorf("%w: %s.%s is %s, which is the override signature before shallow "+
+					"parameter was added, expected %s", ErrOverrideSignature, modelName(model), name, got, want))
+			default:
+				errs = append(errs, fmt.Errorf("%w: %s.%s is %s, expected %s",
+					ErrOverrideSignature, modelName(model), name, got, want))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,167 @@
+package ezg
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Page is a page of records retrieved with offset pagination, together with total count of matching records.
+type Page[t any] struct {
+	Items []t
+	// Total is the number of records matching the conditions, regardless of offset and limit.
+	Total  uint64
+	Offset uint64
+	// Limit is the requested page size, 0 if page was requested without limit.
+	Limit   uint64
+	HasNext bool
+	HasPrev bool
+}
+
+// pageRow is used to scan a model along with window function count.
+type pageRow[t any] struct {
+	Row   t      `gorm:"embedded"`
+	Total uint64 `gorm:"column:ezg_total"`
+}
+
+// rawWhere is custom WHERE SQL of the Sql finder variants.
+type rawWhere struct {
+	sql  string
+	args []interface{}
+}
+
+// WindowCount returns a copy of the wrapper whose FindPage variants retrieve total count with COUNT(*) OVER() window
+// function in the same query as the page, instead of issuing separate count query. Database must support window
+// functions (PostgreSQL, SQLite 3.25+, MySQL 8+). Models overriding FindPaginated or Count keep using the overrides.
+func (q Q[t]) WindowCount() Q[t] {
+	q.window = true
+	return q
+}
+
+// FindPage retrieves a page of models like FindPaginated, along with total count of models matching the same
+// conditions (computed like Count) and page metadata.
+// Instead of using gorm.ErrRecordNotFound it will return page with empty slice and nil error.
+func (q Q[t]) FindPage(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool) (Page[t], error) {
+	return q.findPage(db, offset, limit, reverseOrder, false, nil)
+}
+
+// ShallowFindPage is FindPage without preloading any associations.
+func (q Q[t]) ShallowFindPage(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool) (Page[t], error) {
+	return q.findPage(db, offset, limit, reverseOrder, true, nil)
+}
+
+// FindPageSql retrieves a page of models like FindPaginatedSql, along with total count of models matching the same
+// custom WHERE SQL (computed like CountSql) and page metadata.
+// Instead of using gorm.ErrRecordNotFound it will return page with empty slice and nil error.
+func (q Q[t]) FindPageSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, sql string, sqlArgs ...interface{}) (Page[t], error) {
+	return q.findPage(db, offset, limit, reverseOrder, false, &rawWhere{sql: sql, args: sqlArgs})
+}
+
+// ShallowFindPageSql is FindPageSql without preloading any associations.
+func (q Q[t]) ShallowFindPageSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, sql string, sqlArgs ...interface{}) (Page[t], error) {
+	return q.findPage(db, offset, limit, reverseOrder, true, &rawWhere{sql: sql, args: sqlArgs})
+}
+
+func (q Q[t]) findPage(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder, shallow bool, raw *rawWhere) (Page[t], error) {
+	op := "FindPage"
+	if raw != nil {
+		op = "FindPageSql"
+	}
+	op = opName(op, shallow)
+	db = q.session(db)
+	page := Page[t]{Items: make([]t, 0)}
+	if offset != nil {
+		page.Offset = *offset
+	}
+	if limit != nil {
+		page.Limit = *limit
+	}
+
+	var err error
+	haveTotal := false
+	if q.window && !q.pageOverridden(raw != nil) {
+		page.Items, page.Total, err = q.findCounted(op, db, offset, limit, reverseOrder, shallow, raw)
+		// past the last page there is no row to carry the count
+		haveTotal = len(page.Items) > 0 || page.Offset == 0
+	} else if raw == nil {
+		page.Items, err = q.findPaginated(db, offset, limit, reverseOrder, shallow)
+	} else {
+		page.Items, err = q.findPaginatedSql(db, offset, limit, reverseOrder, shallow, raw.sql, raw.args...)
+	}
+	if err != nil {
+		return page, q.fail(op, nil, err)
+	}
+	if !haveTotal {
+		if raw == nil {
+			page.Total, err = q.Count(db)
+		} else {
+			page.Total, err = q.CountSql(db, raw.sql, raw.args...)
+		}
+		if err != nil {
+			return page, q.fail(op, nil, err)
+		}
+	}
+
+	page.HasPrev = page.Offset > 0
+	page.HasNext = page.Offset+uint64(len(page.Items)) < page.Total
+	return page, nil
+}
+
+// pageOverridden reports whether the model overrides any of the functions FindPage is composed of.
+func (q Q[t]) pageOverridden(sql bool) bool {
+	if sql {
+		_, find := interface{}(q.obj).(FindPaginatedSqlOverrider[t])
+		_, count := interface{}(q.obj).(CountSqlOverrider)
+		return find || count
+	}
+	_, find := interface{}(q.obj).(FindPaginatedOverrider[t])
+	_, count := interface{}(q.obj).(CountOverrider)
+	return find || count
+}
+
+// findCounted retrieves a page of models together with COUNT(*) OVER() of the whole result.
+func (q Q[t]) findCounted(op string, db *gorm.DB, offset *uint64, limit *uint64, reverseOrder, shallow bool, raw *rawWhere) ([]t, uint64, error) {
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, 0, err
+	}
+	// the model is scanned as embedded struct of pageRow, so query table instead of model
+	qry := db.Table(s.Table)
+	if raw == nil {
+		qry, err = q.conditions(qry)
+	} else {
+		qry, err = q.filtered(qry.Where(raw.sql, raw.args...))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if qry, err = q.ordered(qry, reverseOrder); err != nil {
+		return nil, 0, err
+	}
+	if offset != nil {
+		qry = qry.Offset(int(*offset))
+	}
+	if limit != nil {
+		qry = qry.Limit(int(*limit))
+	}
+
+	if qry, err = q.preloadAs(qry, shallow, "Row."); err != nil {
+		return nil, 0, err
+	}
+	rows := make([]pageRow[t], 0)
+	tx := qry.Select("?.*, COUNT(*) OVER() AS ezg_total", clause.Table{Name: clause.CurrentTable}).Find(&rows)
+	if tx.Error != nil {
+		return nil, 0, q.fail(op, tx, tx.Error)
+	}
+	out := make([]t, len(rows))
+	total := uint64(0)
+	for i := range rows {
+		out[i] = rows[i].Row
+		total = rows[i].Total
+	}
+	if err = counted(tx, reflect.ValueOf(out)); err != nil {
+		return nil, 0, q.fail(op, tx, err)
+	}
+	return out, total, nil
+}
@@ -0,0 +1,144 @@
+package ezg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PreloadSource tells where a preload path of a model comes from.
+type PreloadSource uint8
+
+const (
+	// SourceRequiresPreload is a path returned by single relation RequiresPreload.
+	SourceRequiresPreload PreloadSource = iota + 1
+	// SourceRequiresPreloadMulti is a path returned by multi relation RequiresPreload.
+	SourceRequiresPreloadMulti
+	// SourceAutopreload is a relation found by autopreloading.
+	SourceAutopreload
+	// SourceTag is a relation found by autopreloading, configured by ezg tag (conditions, preload-depth or join).
+	SourceTag
+)
+
+func (s PreloadSource) String() string {
+	switch s {
+	case SourceRequiresPreload:
+		return "RequiresPreload"
+	case SourceRequiresPreloadMulti:
+		return "RequiresPreload (multi)"
+	case SourceAutopreload:
+		return "autopreload"
+	case SourceTag:
+		return "tag"
+	}
+	return fmt.Sprintf("PreloadSource(%d)", uint8(s))
+}
+
+// SkipReason tells why autopreloading skipped a field.
+type SkipReason uint8
+
+const (
+	// SkipNoPreloadTag is a relation tagged with ezg:"no-preload".
+	SkipNoPreloadTag SkipReason = iota + 1
+	// SkipNotRelation is a field without database column, which gorm does not recognize as relation (such as field
+	// tagged gorm:"-" or relation with missing foreign key).
+	SkipNotRelation
+	// SkipCycle is a relation leading to a model that is already preloaded on the same path.
+	SkipCycle
+	// SkipDepthLimit is a relation that was already followed as many times as its ezg:"preload-depth=N" allows.
+	SkipDepthLimit
+)
+
+func (r SkipReason) String() string {
+	switch r {
+	case SkipNoPreloadTag:
+		return "no-preload tag"
+	case SkipNotRelation:
+		return "not a relation"
+	case SkipCycle:
+		return "cycle"
+	case SkipDepthLimit:
+		return "preload-depth limit"
+	}
+	return fmt.Sprintf("SkipReason(%d)", uint8(r))
+}
+
+// PreloadPath is a relation path preloaded by non-shallow finders.
+type PreloadPath struct {
+	Path   string
+	Source PreloadSource
+	// Conditional is true if the preload query is modified, by gorm function of RequiresPreload or by ezg tag.
+	Conditional bool
+	// Joined is true if the relation is preloaded by JOIN in the query of the parent, see Q.Joined.
+	Joined bool
+}
+
+// SkippedPreload is a field that autopreloading did not preload.
+type SkippedPreload struct {
+	Path   string
+	Reason SkipReason
+}
+
+// Preloads describes preloading of a model, see PreloadPlan.
+type Preloads struct {
+	Model string
+	// Paths are preloaded paths, in order in which they are applied.
+	Paths []PreloadPath
+	// Skipped are fields skipped by autopreloading. It is empty for models implementing RequiresPreload.
+	Skipped []SkippedPreload
+}
+
+// String formats the plan with one path per line, such as:
+// Author
+//
+//	Posts (autopreload)
+//	Posts.Images (tag, conditional)
+//	skipped Posts.Author (cycle)
+func (p Preloads) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(p.Model)
+	for _, path := range p.Paths {
+		sb.WriteString("\n\t" + path.Path + " (" + path.Source.String())
+		if path.Conditional {
+			sb.WriteString(", conditional")
+		}
+		if path.Joined {
+			sb.WriteString(", joined")
+		}
+		sb.WriteString(")")
+	}
+	for _, skipped := range p.Skipped {
+		sb.WriteString("\n\tskipped " + skipped.Path + " (" + skipped.Reason.String() + ")")
+	}
+	return sb.String()
+}
+
+// PreloadPlan returns relation paths that non-shallow finders of model T preload (before per-call selection with
+// With, Without and PreloadOnly), where each of them comes from, and fields that were not preloaded and why. Errors
+// are the same as of the finders, such as *PreloadError. It's meant for tests and debugging:
+// plan, err := ezg.PreloadPlan[Author]()
+// fmt.Println(plan)
+func PreloadPlan[T any]() (Preloads, error) {
+	model := new(T)
+	out := Preloads{Model: modelName(model), Paths: make([]PreloadPath, 0), Skipped: make([]SkippedPreload, 0)}
+	specs, err := preloads(model, nil)
+	if err != nil {
+		return out, err
+	}
+	_, single := interface{}(model).(singlePreloader)
+	_, multi := interface{}(model).(multiPreloader)
+	if !single && !multi {
+		// walk again to collect skipped fields, which are not cached
+		if specs, err = walkPreloads(model, nil, &out.Skipped); err != nil {
+			return out, err
+		}
+	}
+	for _, spec := range specs {
+		out.Paths = append(out.Paths, PreloadPath{
+			Path:        spec.name,
+			Source:      spec.source,
+			Conditional: spec.cond != nil,
+			Joined:      spec.join,
+		})
+	}
+	return out, nil
+}
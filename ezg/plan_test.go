@@ -0,0 +1,84 @@
+package ezg
+
+import (
+	"errors"
+	"testing"
+)
+
+type plannedDoc struct {
+	ID       uint
+	Author   cyclicAuthor `gorm:"foreignKey:AuthorID"`
+	AuthorID uint
+	Drafts   []cyclicPost     `gorm:"-"`
+	Hidden   []cyclicPost     `gorm:"foreignKey:AuthorID" ezg:"no-preload"`
+	Tree     []cyclicCategory `gorm:"foreignKey:ParentID"`
+}
+
+func Test_PreloadPlan(t *testing.T) {
+	plan, err := PreloadPlan[plannedDoc]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "plannedDoc" +
+		"\n\tAuthor (autopreload)" +
+		"\n\tAuthor.Posts (autopreload)" +
+		"\n\tTree (autopreload)" +
+		"\n\tTree.Tree (tag)" +
+		"\n\tTree.Tree.Tree (tag)" +
+		"\n\tTree.Tree.Tree.Tree (tag)" +
+		"\n\tskipped Author.Posts.Author (cycle)" +
+		"\n\tskipped Author.Friends (cycle)" +
+		"\n\tskipped Drafts (not a relation)" +
+		"\n\tskipped Hidden (no-preload tag)" +
+		"\n\tskipped Tree.Children (cycle)" +
+		"\n\tskipped Tree.Tree.Children (cycle)" +
+		"\n\tskipped Tree.Tree.Tree.Children (cycle)" +
+		"\n\tskipped Tree.Tree.Tree.Tree.Children (cycle)" +
+		"\n\tskipped Tree.Tree.Tree.Tree.Tree (preload-depth limit)"
+	if plan.String() != want {
+		t.Fatalf("wanted plan\n%s\ngot\n%s", want, plan)
+	}
+
+	if plan, err = PreloadPlan[validMulti](); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Paths) != 2 || plan.Paths[1].Path != "Bar" || plan.Paths[1].Source != SourceRequiresPreloadMulti || len(plan.Skipped) != 0 {
+		t.Fatalf("unexpected plan %s", plan)
+	}
+	if _, err = PreloadPlan[mismatchedMulti](); !errors.Is(err, ErrPreloadMismatch) {
+		t.Fatalf("expected ErrPreloadMismatch, got %v", err)
+	}
+}
+
+type joinedTarget struct {
+	ID    uint
+	Posts []cyclicPost `gorm:"foreignKey:AuthorID" ezg:"join"`
+}
+
+type joinedDoc struct {
+	ID       uint
+	TargetID uint
+	Target   joinedTarget `ezg:"join"`
+	Plain    joinedTarget `gorm:"foreignKey:TargetID" ezg:"join,where=id > 0"`
+}
+
+func Test_PreloadPlanJoined(t *testing.T) {
+	plan, err := PreloadPlan[joinedDoc]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "joinedDoc" +
+		"\n\tTarget (tag, joined)" +
+		"\n\tTarget.Posts (tag)" +
+		"\n\tTarget.Posts.Author (autopreload)" +
+		"\n\tPlain (tag, conditional)" +
+		"\n\tPlain.Posts (tag)" +
+		"\n\tPlain.Posts.Author (autopreload)" +
+		"\n\tskipped Target.Posts.Author.Posts (cycle)" +
+		"\n\tskipped Target.Posts.Author.Friends (cycle)" +
+		"\n\tskipped Plain.Posts.Author.Posts (cycle)" +
+		"\n\tskipped Plain.Posts.Author.Friends (cycle)"
+	if plan.String() != want {
+		t.Fatalf("wanted plan\n%s\ngot\n%s", want, plan)
+	}
+}
@@ -0,0 +1,292 @@
+package ezg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// preloadSpec is a single relation to preload, with optional gorm function applied to the preload query.
+type preloadSpec struct {
+	name   string
+	cond   func(orm *gorm.DB) *gorm.DB
+	source PreloadSource
+	// join preloads the relation by JOIN in the query of the parent.
+	join bool
+}
+
+type singlePreloader interface {
+	RequiresPreload() (string, func(orm *gorm.DB) *gorm.DB)
+}
+
+// multiPreloader is support for multi table preload.
+type multiPreloader interface {
+	RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB)
+}
+
+// preloads resolves relations of model to preload, declared with RequiresPreload or found by autopreloading with
+// naming strategy of db.
+func preloads(model interface{}, db *gorm.DB) ([]preloadSpec, error) {
+	if o, ok := model.(singlePreloader); ok {
+		a, b := o.RequiresPreload()
+		return []preloadSpec{{name: a, cond: b, source: SourceRequiresPreload}}, nil
+	}
+	if o, ok := model.(multiPreloader); ok {
+		a, b := o.RequiresPreload()
+		// Length of gorm functions (preload conditions or modifications) should be either 0 (meaning no specific
+		// conditions for all preloaded tables) or equal to the length of preloaded tables (meaning each preload has a
+		// corresponding condition or modification, even if it's nil - which is valid usage). Anything else is an
+		// inconsistency in the model's definition, which makes every read of the table fail.
+		if len(b) != 0 && len(a) != len(b) {
+			return nil, &PreloadError{Model: modelName(model), Err: ErrPreloadMismatch, Detail: fmt.Sprintf(
+				"length of gorm functions must be either 0 or equal to length of preloaded tables, instead got "+
+					"len(tables) = %d, len(gormFuncs) = %d. Gorm function is always allowed to be nil for selective usage",
+				len(a), len(b))}
+		}
+		out := make([]preloadSpec, len(a))
+		for i := range a {
+			out[i].name = a[i]
+			out[i].source = SourceRequiresPreloadMulti
+			if len(b) != 0 {
+				out[i].cond = b[i]
+			}
+		}
+		return out, nil
+	}
+
+	return autoPreloads(model, db)
+}
+
+// modelName returns name of the model type, dereferencing pointers.
+func modelName(model interface{}) string {
+	typ, ok := model.(reflect.Type)
+	if !ok {
+		typ = reflect.TypeOf(model)
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
+
+// With returns a copy of the wrapper whose finders preload listed relation paths (such as "Posts.Images") in addition
+// to preloads of the model. Conditions of the added preloads come from ezg tags of the relation fields. Paths are
+// validated against relations of the model when the query runs, unknown paths fail it with ErrUnknownRelation.
+// Shallow finders do not preload anything regardless, models overriding finders preload on their own.
+func (q Q[t]) With(paths ...string) Q[t] {
+	q.with = append(append(make([]string, 0, len(q.with)+len(paths)), q.with...), paths...)
+	return q
+}
+
+// Without returns a copy of the wrapper whose finders do not preload listed relation paths, nor relations nested in
+// them, even if they are preloads of the model or were added by With.
+func (q Q[t]) Without(paths ...string) Q[t] {
+	q.without = append(append(make([]string, 0, len(q.without)+len(paths)), q.without...), paths...)
+	return q
+}
+
+// PreloadOnly returns a copy of the wrapper whose finders preload only listed relation paths instead of preloads of
+// the model. It can be combined with With and Without.
+func (q Q[t]) PreloadOnly(paths ...string) Q[t] {
+	q.only = append(make([]string, 0, len(paths)), paths...)
+	return q
+}
+
+// preloadLimit is a preload with per-parent limit, see Q.WithLimit.
+type preloadLimit struct {
+	path   string
+	n      int
+	orders []Order
+}
+
+// WithLimit returns a copy of the wrapper whose finders preload at most n records of has-many relation path per
+// parent record, such as 5 latest posts of every author:
+// authors, err := W(&Author{}).WithLimit("Posts", 5, Desc("created_at")).Find(orm)
+// The path is added like With, if it's not preloaded already. Orders are validated against the related model, without
+// them order of the ezg tag of the relation is used, or primary key. Where condition of the tag still applies, other
+// conditions of the preload (such as from RequiresPreload) are replaced.
+func (q Q[t]) WithLimit(path string, n int, orders ...Order) Q[t] {
+	q.limits = append(append(make([]preloadLimit, 0, len(q.limits)+1), q.limits...), preloadLimit{
+		path:   path,
+		n:      n,
+		orders: append(make([]Order, 0, len(orders)), orders...),
+	})
+	return q
+}
+
+// Joined returns a copy of the wrapper whose finders preload listed relation paths (adding them like With, if they
+// are not preloads of the model) by LEFT JOIN in the main query instead of separate queries. Only to-one relations
+// (belongs-to, has-one) reached through to-one relations can be joined, other paths, as well as preloads with
+// conditions, are preloaded by separate queries. Custom SQL of Sql finder variants should qualify columns with table
+// name, as joined tables may have columns of the same name.
+func (q Q[t]) Joined(paths ...string) Q[t] {
+	q.joined = append(append(make([]string, 0, len(q.joined)+len(paths)), q.joined...), paths...)
+	return q
+}
+
+// selectedPreloads returns preloads of the model, narrowed or extended by With, WithLimit, Joined, Without and
+// PreloadOnly.
+func (q Q[t]) selectedPreloads(db *gorm.DB) ([]preloadSpec, error) {
+	if q.only == nil && len(q.with) == 0 && len(q.without) == 0 && len(q.joined) == 0 &&
+		len(q.limits) == 0 {
+		return preloads(q.obj, db)
+	}
+	// relations are resolved with naming strategy of db, as limits and joins use their columns in SQL
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]preloadSpec, 0)
+	added := append(append(make([]string, 0, len(q.with)+len(q.joined)+len(q.limits)), q.with...), q.joined...)
+	for _, limit := range q.limits {
+		added = append(added, limit.path)
+	}
+	if q.only != nil {
+		added = append(append(make([]string, 0, len(q.only)+len(added)), q.only...), added...)
+	} else {
+		specs, err := preloads(q.obj, db)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, specs...)
+	}
+	for _, path := range added {
+		rel, err := relationAt(s, path)
+		if err != nil {
+			return nil, err
+		}
+		if hasPreload(out, path) {
+			continue
+		}
+		tag, err := parseRelationTag(rel.Field.StructField)
+		var cond func(orm *gorm.DB) *gorm.DB
+		if err == nil {
+			cond, err = tag.condition(rel)
+		}
+		if err != nil {
+			return nil, &PreloadError{Model: s.Name, Err: ErrPreloadTag, Detail: fmt.Sprintf(
+				"ezg tag of %s: %v", path, err)}
+		}
+		out = append(out, preloadSpec{name: path, cond: cond})
+	}
+	for _, limit := range q.limits {
+		cond, err := limit.condition(s)
+		if err != nil {
+			return nil, err
+		}
+		for i := range out {
+			if out[i].name == limit.path {
+				out[i].cond = cond
+				out[i].join = false
+			}
+		}
+	}
+	for _, path := range q.joined {
+		rels, err := relationsAt(s, path)
+		if err != nil {
+			return nil, err
+		}
+		// ancestors have to be joined too, otherwise the relation is preloaded by separate query
+		name := ""
+		for _, rel := range rels {
+			if !joinable(rel) {
+				break
+			}
+			name += rel.Name
+			for i := range out {
+				if out[i].name == name && out[i].cond == nil {
+					out[i].join = true
+				}
+			}
+			name += "."
+		}
+	}
+	for _, path := range q.without {
+		if _, err := relationAt(s, path); err != nil {
+			return nil, err
+		}
+		kept := out[:0:0]
+		for _, spec := range out {
+			if spec.name != path && !strings.HasPrefix(spec.name, path+".") {
+				kept = append(kept, spec)
+			}
+		}
+		out = kept
+	}
+	return out, nil
+}
+
+// condition returns gorm function applying the limit, orders and where condition of the ezg tag to preload query.
+func (l preloadLimit) condition(s *schema.Schema) (func(orm *gorm.DB) *gorm.DB, error) {
+	if l.n < 1 {
+		return nil, fmt.Errorf("%w: limit of %s must be positive, got %d", ErrInvalidPreload, l.path, l.n)
+	}
+	rel, err := relationAt(s, l.path)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := parseRelationTag(rel.Field.StructField)
+	if err != nil {
+		return nil, &PreloadError{Model: s.Name, Err: ErrPreloadTag, Detail: fmt.Sprintf(
+			"ezg tag of %s: %v", l.path, err)}
+	}
+	var order clause.Expression
+	if len(l.orders) > 0 {
+		orders, err := orderBy(rel.FieldSchema, l.orders, false)
+		if err != nil {
+			return nil, err
+		}
+		order = orders.Expression
+	} else if tag.order != "" {
+		order = clause.Expr{SQL: tag.order}
+	}
+	cond, err := preloadCondition(rel, tag.where, order, l.n)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPreload, err)
+	}
+	return cond, nil
+}
+
+func hasPreload(specs []preloadSpec, name string) bool {
+	for _, spec := range specs {
+		if spec.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// relationAt resolves relation path, such as "Posts.Images" or "Meta.Editor" (relation of embedded struct), to the
+// last relation on the path.
+func relationAt(s *schema.Schema, path string) (*schema.Relationship, error) {
+	rels, err := relationsAt(s, path)
+	if err != nil {
+		return nil, err
+	}
+	return rels[len(rels)-1], nil
+}
+
+// relationsAt resolves relation path to every relation on the path.
+func relationsAt(s *schema.Schema, path string) ([]*schema.Relationship, error) {
+	rels := &s.Relationships
+	out := make([]*schema.Relationship, 0)
+	names := strings.Split(path, ".")
+	for i := 0; i < len(names); i++ {
+		if embedded, ok := rels.EmbeddedRelations[names[i]]; ok && i < len(names)-1 {
+			rels = embedded
+			continue
+		}
+		rel := rels.Relations[names[i]]
+		if rel == nil {
+			return nil, fmt.Errorf("%w %q in model %s", ErrUnknownRelation, path, s.Name)
+		}
+		out = append(out, rel)
+		rels = &rel.FieldSchema.Relationships
+	}
+	return out, nil
+}
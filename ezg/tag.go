@@ -0,0 +1,107 @@
+package ezg
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	tagPreload      = "preload"
+	tagNoPreload1   = "no-preload"
+	tagNoPreload2   = "nopreload"
+	tagPreloadDepth = "preload-depth"
+	tagWhere        = "where"
+	tagOrder        = "order"
+	tagLimit        = "limit"
+	tagJoin         = "join"
+	tagCount        = "count"
+)
+
+// relationTag is parsed ezg struct tag of a relation field, such as
+// Posts []Post `ezg:"preload,order=created_at desc,where=published = true,limit=10"`
+// Options are separated by comma. Values of where and order are SQL, which may contain commas too, so comma followed
+// by anything else than known option continues the value.
+type relationTag struct {
+	noPreload bool
+	// depth is maximum number of times the relation is followed on single path, 0 means the relation is not followed
+	// when it closes a cycle.
+	depth int
+	where string
+	order string
+	limit int
+	// join preloads to-one relation by JOIN in the query of the parent, instead of separate query.
+	join bool
+	// count is association counted into the field (which is not a relation), see countFields.
+	count string
+}
+
+// parseRelationTag parses ezg tag of relation field.
+func parseRelationTag(field reflect.StructField) (relationTag, error) {
+	out := relationTag{}
+	raw, ok := field.Tag.Lookup("ezg")
+	if !ok {
+		return out, nil
+	}
+	opts := make([][2]string, 0)
+	for _, part := range strings.Split(raw, ",") {
+		name, value, _ := strings.Cut(part, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case tagPreload, tagNoPreload1, tagNoPreload2, tagPreloadDepth, tagWhere, tagOrder, tagLimit, tagJoin, tagCount:
+			opts = append(opts, [2]string{name, strings.TrimSpace(value)})
+			continue
+		}
+		if last := len(opts) - 1; last >= 0 && (opts[last][0] == tagWhere || opts[last][0] == tagOrder) {
+			opts[last][1] += "," + strings.TrimRight(part, " ")
+			continue
+		}
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		return out, fmt.Errorf("unknown option %q", strings.TrimSpace(part))
+	}
+
+	var err error
+	for _, opt := range opts {
+		switch opt[0] {
+		case tagNoPreload1, tagNoPreload2:
+			out.noPreload = true
+		case tagJoin:
+			out.join = true
+		case tagCount:
+			if out.count = opt[1]; out.count == "" {
+				return out, fmt.Errorf("%s must name an association", tagCount)
+			}
+		case tagPreloadDepth:
+			if out.depth, err = strconv.Atoi(opt[1]); err != nil || out.depth < 1 {
+				return out, fmt.Errorf("%s must be positive integer, got %q", tagPreloadDepth, opt[1])
+			}
+		case tagWhere:
+			out.where = opt[1]
+		case tagOrder:
+			out.order = opt[1]
+		case tagLimit:
+			if out.limit, err = strconv.Atoi(opt[1]); err != nil || out.limit < 1 {
+				return out, fmt.Errorf("%s must be positive integer, got %q", tagLimit, opt[1])
+			}
+		}
+	}
+	return out, nil
+}
+
+// condition returns gorm function applying where, order and limit of the tag to the preload query of rel, nil if
+// there is nothing to apply. Unlike with gorm Preload, limit applies to each parent separately (see perParentLimit),
+// so it's supported only for has-many relations.
+func (t relationTag) condition(rel *schema.Relationship) (func(orm *gorm.DB) *gorm.DB, error) {
+	var order clause.Expression
+	if t.order != "" {
+		order = clause.Expr{SQL: t.order}
+	}
+	return preloadCondition(rel, t.where, order, t.limit)
+}
@@ -0,0 +1,35 @@
+package ezg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ParseRelationTag(t *testing.T) {
+	for _, tc := range []struct {
+		tag  string
+		want relationTag
+		err  bool
+	}{
+		{``, relationTag{}, false},
+		{`ezg:"no-preload"`, relationTag{noPreload: true}, false},
+		{`ezg:"NoPreload"`, relationTag{noPreload: true}, false},
+		{`ezg:"preload-depth=3"`, relationTag{depth: 3}, false},
+		{`ezg:"preload,order=name asc,where=active = true,limit=10"`,
+			relationTag{order: "name asc", where: "active = true", limit: 10}, false},
+		{`ezg:"preload,where=kind IN (1, 2),order=kind desc, id"`,
+			relationTag{where: "kind IN (1, 2)", order: "kind desc, id"}, false},
+		{`ezg:"limit=many"`, relationTag{}, true},
+		{`ezg:"preload-depth=0"`, relationTag{}, true},
+		{`ezg:"preload,ordr=name"`, relationTag{}, true},
+	} {
+		field := reflect.StructField{Name: "Rel", Tag: reflect.StructTag(tc.tag)}
+		got, err := parseRelationTag(field)
+		if (err != nil) != tc.err {
+			t.Fatalf("%s: unexpected error %v", tc.tag, err)
+		}
+		if err == nil && got != tc.want {
+			t.Fatalf("%s: wanted %+v, got %+v", tc.tag, tc.want, got)
+		}
+	}
+}
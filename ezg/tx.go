@@ -0,0 +1,98 @@
+package ezg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// TxOptions configure InTx. Zero value (or nil) runs the function at most 3 times in transaction of default isolation
+// level, with exponential backoff starting at 10ms.
+type TxOptions struct {
+	// Isolation is isolation level of the transaction, sql.LevelDefault leaves it to the database.
+	Isolation sql.IsolationLevel
+	// ReadOnly starts read only transaction.
+	ReadOnly bool
+	// MaxAttempts is maximum number of times the function runs, 0 means 3.
+	MaxAttempts int
+	// Backoff returns delay before given retry (1 for the first retry), nil means exponential backoff starting at 10ms
+	// (capped at 1s) with random jitter of up to the same duration.
+	Backoff func(retry int) time.Duration
+}
+
+const defaultTxAttempts = 3
+
+// InTx runs fn in a transaction, committing it if fn returns nil and rolling it back otherwise (or on panic, which is
+// propagated). When the transaction fails because of conflicting concurrent transaction (see Retryable), fn runs
+// again in new transaction, after backoff, until it succeeds or MaxAttempts is reached, in which case error of the last
+// attempt is returned. fn must therefore do nothing but database operations through tx (for example with Q methods),
+// or be safe to repeat:
+// err := ezg.InTx(ctx, orm, &ezg.TxOptions{Isolation: sql.LevelSerializable}, func(tx *gorm.DB) error {
+// if err := ezg.W(&from).Update(tx); err != nil { return err }
+// return ezg.W(&to).Update(tx)
+// })
+// If db is already a transaction (InTx called from fn of another InTx or gorm Transaction), fn runs in a savepoint
+// instead, which is rolled back if fn fails, without retrying nor options: conflicts abort the whole transaction, so
+// the outermost InTx retries it.
+func InTx(ctx context.Context, db *gorm.DB, opts *TxOptions, fn func(tx *gorm.DB) error) error {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	db = db.WithContext(ctx)
+	if committer, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok && committer != nil {
+		// gorm uses savepoint for nested transaction
+		return db.Transaction(fn)
+	}
+
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultTxAttempts
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	txOpts := &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = db.Transaction(fn, txOpts); err == nil || attempt >= attempts || !Retryable(err) {
+			return err
+		}
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// defaultBackoff doubles delay with every retry, starting at 10ms up to 1s, adding random jitter, so that conflicting
+// transactions don't keep retrying at the same time.
+func defaultBackoff(retry int) time.Duration {
+	d := 10 * time.Millisecond << min(retry-1, 7)
+	d = min(d, time.Second)
+	return d + rand.N(d)
+}
+
+// Retryable reports whether err means that transaction failed because of concurrent transaction and can succeed when
+// repeated: PostgreSQL serialization failure (40001) or deadlock (40P01), or SQLite SQLITE_BUSY (database is locked).
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	// sqlite drivers do not share error type, but they do share messages
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
@@ -0,0 +1,32 @@
+package ezg
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func Test_Retryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{&pgconn.PgError{Code: "40001"}, true},
+		{&pgconn.PgError{Code: "40P01"}, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{&QueryError{Op: "Update", Err: &pgconn.PgError{Code: "40001"}}, true},
+		{fmt.Errorf("commit: %w", errors.New("database is locked")), true},
+		{errors.New("database is locked (5) (SQLITE_BUSY)"), true},
+	} {
+		if got := Retryable(tc.err); got != tc.want {
+			t.Fatalf("%v: wanted %v, got %v", tc.err, tc.want, got)
+		}
+	}
+	if d := defaultBackoff(100); d < 1e9 || d >= 2e9 {
+		t.Fatalf("expected capped backoff, got %v", d)
+	}
+}
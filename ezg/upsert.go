@@ -0,0 +1,274 @@
+package ezg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils"
+)
+
+// UpsertOutcome tells what Upsert did with a model.
+type UpsertOutcome uint8
+
+const (
+	// Inserted means that the model did not conflict with existing record and was inserted.
+	Inserted UpsertOutcome = iota + 1
+	// Updated means that the model conflicted with existing record, which was updated.
+	Updated
+	// Unchanged means that the model conflicted with existing record, which was left as is (UpsertOptions.DoNothing).
+	Unchanged
+)
+
+func (o UpsertOutcome) String() string {
+	switch o {
+	case Inserted:
+		return "inserted"
+	case Updated:
+		return "updated"
+	case Unchanged:
+		return "unchanged"
+	}
+	return fmt.Sprintf("UpsertOutcome(%d)", uint8(o))
+}
+
+// UpsertOptions configure Upsert and UpsertMany. Zero value (or nil) updates every column of the record that
+// conflicts on primary key.
+type UpsertOptions struct {
+	// Columns are the conflict target, gorm field or database column names, which must be covered by unique index or
+	// constraint. Without Columns and Index, primary key is the target.
+	Columns []string
+	// Index is name of unique index of the model (as declared by gorm uniqueIndex tag, e.g. idx_gadgets_serial), whose
+	// columns (and condition, for partial index) are the conflict target.
+	Index string
+	// Update lists fields or columns updated on conflict, all columns (except primary key and creation time) are
+	// updated if it's empty.
+	Update []string
+	// DoNothing leaves conflicting records unchanged.
+	DoNothing bool
+	// BatchSize is number of models upserted by single statement of UpsertMany, 0 means 1000.
+	BatchSize int
+}
+
+// Upsert inserts the wrapped model, or updates the record it conflicts with (INSERT ... ON CONFLICT), reporting
+// which of it happened:
+// outcome, err := W(&Gadget{Serial: "a-1", Weight: 2}).Upsert(orm, &UpsertOptions{Columns: []string{"serial"}})
+// Primary key of the conflicting record is set on the model. Associations are not upserted. To tell inserts from
+// updates, records conflicting with the model are looked up before the upsert in the same transaction, so a record
+// inserted concurrently in between is reported as Inserted. Errors are *QueryError, such as ErrUnknownColumn for
+// unknown column of the options.
+func (q Q[t]) Upsert(db *gorm.DB, opts *UpsertOptions) (UpsertOutcome, error) {
+	models := []t{*q.obj}
+	out, err := upsert(q, "Upsert", q.session(db), models, opts)
+	*q.obj = models[0]
+	if err != nil {
+		return 0, err
+	}
+	return out[0], nil
+}
+
+// UpsertMany is Upsert of many models, in batches and single transaction (or savepoint, if db is a transaction
+// already), reporting outcome of every model. Models must not conflict with each other.
+func UpsertMany[t any](db *gorm.DB, models []t, opts *UpsertOptions) ([]UpsertOutcome, error) {
+	return upsert(W(new(t)), "UpsertMany", db, models, opts)
+}
+
+func upsert[t any](q Q[t], op string, db *gorm.DB, models []t, opts *UpsertOptions) ([]UpsertOutcome, error) {
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+	out := make([]UpsertOutcome, len(models))
+	if len(models) == 0 {
+		return out, nil
+	}
+	s, err := q.schema(db)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	onConflict, target, err := conflictClause(s, opts)
+	if err != nil {
+		return nil, q.fail(op, nil, err)
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	// keys generated by the database are unknown before insert, so they can't conflict
+	byPrimaryKey := len(target) == len(s.PrimaryFields) && target[0].PrimaryKey
+
+	var failed *gorm.DB
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(models); start += size {
+			batch := models[start:min(start+size, len(models))]
+			existing := make(map[string]interface{})
+			if failed, err = lookupKeys(tx, s, target, batch, byPrimaryKey, existing); err != nil {
+				return err
+			}
+			if failed = tx.Omit(clause.Associations).Clauses(onConflict).Create(batch); failed.Error != nil {
+				return failed.Error
+			}
+			// keys returned by the upsert are not reliable when some records are left unchanged, so they are looked up
+			// again, unless they are the target
+			stored := existing
+			if !byPrimaryKey {
+				stored = make(map[string]interface{})
+				if failed, err = lookupKeys(tx, s, target, batch, false, stored); err != nil {
+					return err
+				}
+			}
+			for i := range batch {
+				key, _ := fieldsKey(tx.Statement.Context, target, reflect.ValueOf(&batch[i]).Elem(), byPrimaryKey)
+				_, ok := existing[key]
+				switch {
+				case !ok:
+					out[start+i] = Inserted
+				case onConflict.DoNothing:
+					out[start+i] = Unchanged
+				default:
+					out[start+i] = Updated
+				}
+				if pk, ok := stored[key]; ok && !byPrimaryKey {
+					if err = setPrimaryKey(tx.Statement.Context, s, reflect.ValueOf(&batch[i]).Elem(), pk); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, q.fail(op, failed, err)
+	}
+	return out, nil
+}
+
+// conflictClause builds ON CONFLICT clause of the options, also returning fields of the conflict target.
+func conflictClause(s *schema.Schema, opts *UpsertOptions) (clause.OnConflict, []*schema.Field, error) {
+	onConflict := clause.OnConflict{}
+	target := make([]*schema.Field, 0)
+	switch {
+	case opts.Index != "":
+		var index *schema.Index
+		for _, idx := range s.ParseIndexes() {
+			if idx.Name == opts.Index && idx.Class == "UNIQUE" {
+				index = idx
+			}
+		}
+		if index == nil {
+			return onConflict, nil, fmt.Errorf("LOGIC ERROR: model %s has no unique index %s", s.Name, opts.Index)
+		}
+		for _, opt := range index.Fields {
+			target = append(target, opt.Field)
+		}
+		if index.Where != "" {
+			onConflict.TargetWhere = clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: index.Where}}}
+		}
+	case len(opts.Columns) > 0:
+		for _, name := range opts.Columns {
+			field := s.LookUpField(name)
+			if field == nil || field.DBName == "" {
+				return onConflict, nil, fmt.Errorf("%w %q in model %s", ErrUnknownColumn, name, s.Name)
+			}
+			target = append(target, field)
+		}
+	default:
+		if len(s.PrimaryFields) == 0 {
+			return onConflict, nil, fmt.Errorf("LOGIC ERROR: %w: model %s has no primary key", ErrNotGormModel, s.Name)
+		}
+		target = append(target, s.PrimaryFields...)
+	}
+	for _, field := range target {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+	}
+
+	switch {
+	case opts.DoNothing:
+		onConflict.DoNothing = true
+	case len(opts.Update) > 0:
+		cols := make([]string, 0, len(opts.Update))
+		for _, name := range opts.Update {
+			col, err := column(s, name)
+			if err != nil {
+				return onConflict, nil, err
+			}
+			cols = append(cols, col.Name)
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(cols)
+	default:
+		onConflict.UpdateAll = true
+	}
+	return onConflict, target, nil
+}
+
+// lookupKeys finds records conflicting with models on target fields, storing their primary key in existing under key
+// of the target fields (see fieldsKey).
+func lookupKeys[t any](tx *gorm.DB, s *schema.Schema, target []*schema.Field, models []t, skipZero bool,
+	existing map[string]interface{}) (*gorm.DB, error) {
+	ctx := tx.Statement.Context
+	keys := make([]interface{}, 0, len(models))
+	for i := range models {
+		if _, values := fieldsKey(ctx, target, reflect.ValueOf(&models[i]).Elem(), skipZero); values != nil {
+			keys = append(keys, values)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if len(target) == 1 {
+		for i := range keys {
+			keys[i] = keys[i].([]interface{})[0]
+		}
+	}
+	cols := make([]string, 0, len(target)+len(s.PrimaryFields))
+	for _, field := range append(append(make([]*schema.Field, 0), target...), s.PrimaryFields...) {
+		cols = append(cols, field.DBName)
+	}
+	found := make([]t, 0)
+	// soft deleted records conflict too
+	lookup := tx.Unscoped().Select(cols).Where(fieldsIn(target, keys)).Find(&found)
+	if lookup.Error != nil {
+		return lookup, lookup.Error
+	}
+	for i := range found {
+		rv := reflect.ValueOf(&found[i]).Elem()
+		key, _ := fieldsKey(ctx, target, rv, false)
+		existing[key] = primaryKeyOf(ctx, s, rv)
+	}
+	return nil, nil
+}
+
+// fieldsKey returns values of fields of model along with their string key, nil values if skipZero is set and any of
+// them is zero.
+func fieldsKey(ctx context.Context, fields []*schema.Field, model reflect.Value, skipZero bool) (string, []interface{}) {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		v, zero := field.ValueOf(ctx, model)
+		if zero && skipZero {
+			return "", nil
+		}
+		values[i] = v
+	}
+	return utils.ToStringKey(values...), values
+}
+
+// primaryKeyOf returns values of primary key fields of model.
+func primaryKeyOf(ctx context.Context, s *schema.Schema, model reflect.Value) []interface{} {
+	out := make([]interface{}, len(s.PrimaryFields))
+	for i, field := range s.PrimaryFields {
+		out[i], _ = field.ValueOf(ctx, model)
+	}
+	return out
+}
+
+// setPrimaryKey sets primary key fields of model to pk, values returned by primaryKeyOf.
+func setPrimaryKey(ctx context.Context, s *schema.Schema, model reflect.Value, pk interface{}) error {
+	for i, field := range s.PrimaryFields {
+		if err := field.Set(ctx, model, pk.([]interface{})[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package ezg
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Validate checks definitions of models, returning (joined) errors that finders would otherwise return on the first
+// read of the model, such as *PreloadError for inconsistent RequiresPreload, and ErrOverrideSignature for methods
+// that look like overrides but are not used as such because of their signature. It's meant to be called from a unit test
+// or during startup:
+// if err := ezg.Validate(&User{}, &Article{}); err != nil { ... }
+func Validate(models ...interface{}) error {
+	errs := make([]error, 0)
+	for _, model := range models {
+		model = pointerTo(model)
+		if _, err := preloads(model, nil); err != nil {
+			errs = append(errs, err)
+		}
+		if _, err := countFields(model, nil); err != nil {
+			errs = append(errs, err)
+		}
+		errs = append(errs, validateOverrides(model)...)
+	}
+	return errors.Join(errs...)
+}
+
+// pointerTo returns model as pointer, so that methods with pointer receivers are visible like they are for Q.
+func pointerTo(model interface{}) interface{} {
+	rv := reflect.ValueOf(model)
+	if rv.Kind() == reflect.Ptr {
+		return model
+	}
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	return ptr.Interface()
+}
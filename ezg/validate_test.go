@@ -0,0 +1,61 @@
+package ezg
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type validSingle struct{}
+
+func (v *validSingle) RequiresPreload() (string, func(orm *gorm.DB) *gorm.DB) {
+	return "Foo", nil
+}
+
+type validMulti struct{}
+
+func (v *validMulti) RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB) {
+	return []string{"Foo", "Bar"}, []func(orm *gorm.DB) *gorm.DB{nil, nil}
+}
+
+type mismatchedMulti struct{}
+
+func (m *mismatchedMulti) RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB) {
+	return []string{"Foo", "Bar"}, []func(orm *gorm.DB) *gorm.DB{nil}
+}
+
+type recursive struct {
+	ID       uint
+	ParentID uint
+	Children []recursive `gorm:"foreignKey:ParentID" ezg:"preload-depth=1000"`
+}
+
+type validAuto struct {
+	ID    uint
+	Items []validAutoItem
+}
+
+type validAutoItem struct {
+	ID          uint
+	ValidAutoID uint
+	Name        string
+}
+
+func Test_Validate(t *testing.T) {
+	if err := Validate(&validSingle{}, validMulti{}, &validAuto{}); err != nil {
+		t.Fatalf("expected valid models, got %v", err)
+	}
+
+	err := Validate(&validSingle{}, mismatchedMulti{}, &recursive{})
+	if !errors.Is(err, ErrPreloadMismatch) {
+		t.Fatalf("expected ErrPreloadMismatch, got %v", err)
+	}
+	if !errors.Is(err, ErrPreloadTooDeep) {
+		t.Fatalf("expected ErrPreloadTooDeep, got %v", err)
+	}
+	preloadErr := &PreloadError{}
+	if !errors.As(err, &preloadErr) || preloadErr.Model != "mismatchedMulti" {
+		t.Fatalf("expected PreloadError of mismatchedMulti, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ezg
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AllowAll returns a copy of the wrapper whose UpdateWhere and DeleteWhere (and their Sql variants) may affect every
+// record of the table. Without it, they refuse to run without conditions, returning ErrNoConditions.
+func (q Q[t]) AllowAll() Q[t] {
+	q.allowAll = true
+	return q
+}
+
+// UpdateWhere updates every record matching the wrapped model (non-zero fields and fields registered with Match) and
+// filters registered with Where, the same conditions Find uses, returning number of updated records:
+// n, err := W(&Task{ProjectID: id}).Where(Eq("done", true)).UpdateWhere(orm, map[string]interface{}{"archived": true})
+// Keys of changes are gorm field or database column names, validated against the model (ErrUnknownColumn), values
+// may also be gorm.Expr. Soft deleted records are not updated, hooks (such as BeforeUpdate) and overrides of the model
+// are not called, as no record is loaded.
+// Without any condition it returns ErrNoConditions, see AllowAll.
+func (q Q[t]) UpdateWhere(db *gorm.DB, changes map[string]interface{}) (int64, error) {
+	return q.updateWhere("UpdateWhere", db, changes, nil)
+}
+
+// UpdateWhereSql is UpdateWhere of records matching custom SQL (and filters registered with Where), like FindSql.
+func (q Q[t]) UpdateWhereSql(db *gorm.DB, changes map[string]interface{}, sql string, sqlArgs ...interface{}) (int64, error) {
+	return q.updateWhere("UpdateWhereSql", db, changes, &rawWhere{sql: sql, args: sqlArgs})
+}
+
+// DeleteWhere deletes every record matching the wrapped model and filters registered with Where, the same conditions
+// Find uses, returning number of deleted records. Models with gorm.DeletedAt are soft deleted, like with Delete.
+// Hooks (such as BeforeDelete) and overrides of the model are not called, as no record is loaded.
+// Without any condition it returns ErrNoConditions, see AllowAll.
+func (q Q[t]) DeleteWhere(db *gorm.DB) (int64, error) {
+	return q.deleteWhere("DeleteWhere", db, nil)
+}
+
+// DeleteWhereSql is DeleteWhere of records matching custom SQL (and filters registered with Where), like FindSql.
+func (q Q[t]) DeleteWhereSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (int64, error) {
+	return q.deleteWhere("DeleteWhereSql", db, &rawWhere{sql: sql, args: sqlArgs})
+}
+
+func (q Q[t]) updateWhere(op string, db *gorm.DB, changes map[string]interface{}, raw *rawWhere) (int64, error) {
+	db = q.session(db)
+	s, err := q.schema(db)
+	if err != nil {
+		return 0, q.fail(op, nil, err)
+	}
+	if len(changes) == 0 {
+		return 0, q.fail(op, nil, errors.New("LOGIC ERROR: no changes to update"))
+	}
+	assignments := make(map[string]interface{}, len(changes))
+	for name, value := range changes {
+		col, err := column(s, name)
+		if err != nil {
+			return 0, q.fail(op, nil, err)
+		}
+		assignments[col.Name] = value
+	}
+	qry, err := q.bulkConditions(db, raw)
+	if err != nil {
+		return 0, q.fail(op, nil, err)
+	}
+	tx := qry.Updates(assignments)
+	return tx.RowsAffected, q.fail(op, tx, tx.Error)
+}
+
+func (q Q[t]) deleteWhere(op string, db *gorm.DB, raw *rawWhere) (int64, error) {
+	db = q.session(db)
+	qry, err := q.bulkConditions(db, raw)
+	if err != nil {
+		return 0, q.fail(op, nil, err)
+	}
+	tx := qry.Delete(new(t))
+	return tx.RowsAffected, q.fail(op, tx, tx.Error)
+}
+
+// bulkConditions applies conditions of UpdateWhere and DeleteWhere (or their Sql variants, if raw is set) to db,
+// refusing to continue without conditions unless allowed by AllowAll. Hooks are skipped, gorm would otherwise call
+// them on the zero model the query is built from.
+func (q Q[t]) bulkConditions(db *gorm.DB, raw *rawWhere) (*gorm.DB, error) {
+	qry := db.Session(&gorm.Session{SkipHooks: true}).Model(new(t))
+	var err error
+	if raw == nil {
+		qry, err = q.conditions(qry)
+	} else {
+		if raw.sql != "" {
+			qry = qry.Where(raw.sql, raw.args...)
+		}
+		qry, err = q.filtered(qry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	where, _ := qry.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if len(where.Exprs) > 0 {
+		return qry, nil
+	}
+	if !q.allowAll {
+		return nil, fmt.Errorf("%w of model %s, use AllowAll to affect every record", ErrNoConditions, modelName(q.obj))
+	}
+	return qry.Session(&gorm.Session{AllowGlobalUpdate: true}), nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+func Test_AutopreloadBelongsTo(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Foo{}, &Bar{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&Foo{Model: gorm.Model{ID: 7}, Length: 10}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&Bar{Width: 20, FooId: 7}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	bar, err := ezg.W(&Bar{Width: 20}).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bar.Foo.ID != 7 || bar.Foo.Length != 10 {
+		t.Fatalf("expected Foo to be preloaded, got %+v", bar.Foo)
+	}
+	if bar, err = ezg.W(&Bar{Width: 20}).ShallowFindOne(orm); err != nil || bar.Foo.ID != 0 {
+		t.Fatalf("expected Foo not to be preloaded, got %+v, %v", bar, err)
+	}
+}
+
+func Test_AutopreloadTree(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Category{}); err != nil {
+		t.Fatal(err)
+	}
+	// root > a > b > c
+	parent := (*uint)(nil)
+	for _, name := range []string{"root", "a", "b", "c"} {
+		c := &Category{Name: name, ParentID: parent}
+		if err := ezg.W(c).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+		parent = &c.ID
+	}
+
+	root, err := ezg.W(&Category{Name: "root"}).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 1 || len(root.Children[0].Children) != 1 || root.Children[0].Children[0].Name != "b" {
+		t.Fatalf("expected two levels of children, got %+v", root.Children)
+	}
+	if len(root.Children[0].Children[0].Children) != 0 {
+		t.Fatalf("expected third level not to be preloaded, got %+v", root.Children[0].Children[0].Children)
+	}
+}
+
+func Test_AutopreloadSchema(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Editor{}, &Label{}, &Doc{}); err != nil {
+		t.Fatal(err)
+	}
+	owner, reviewer, watcher := &Editor{Name: "owner"}, &Editor{Name: "reviewer"}, &Editor{Name: "watcher"}
+	for _, e := range []*Editor{owner, reviewer, watcher} {
+		if err := ezg.W(e).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	doc := &Doc{
+		Audit:    Audit{OwnerID: owner.ID},
+		Meta:     DocMeta{ReviewerID: &reviewer.ID},
+		Labels:   []Label{{Name: "draft"}},
+		Watchers: []Editor{*watcher},
+		Keywords: []string{"a", "b"},
+		Blob:     []byte("blob"),
+	}
+	if err := ezg.W(doc).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ezg.W(&Doc{}).FindByID(orm, doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Owner.Name != "owner" {
+		t.Fatalf("expected relation of anonymous embedded struct to be preloaded, got %+v", got.Owner)
+	}
+	if got.Meta.Reviewer == nil || got.Meta.Reviewer.Name != "reviewer" {
+		t.Fatalf("expected relation of embedded struct to be preloaded, got %+v", got.Meta)
+	}
+	if len(got.Labels) != 1 || got.Labels[0].Name != "draft" {
+		t.Fatalf("expected polymorphic relation to be preloaded, got %+v", got.Labels)
+	}
+	if len(got.Watchers) != 1 || got.Watchers[0].Name != "watcher" {
+		t.Fatalf("expected many2many relation to be preloaded, got %+v", got.Watchers)
+	}
+	if len(got.Keywords) != 2 || string(got.Blob) != "blob" {
+		t.Fatalf("expected columns to be read, got %+v, %q", got.Keywords, got.Blob)
+	}
+	if err = ezg.Validate(&Doc{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_AutopreloadTagConditions(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Shelf{}, &Book{}); err != nil {
+		t.Fatal(err)
+	}
+	shelf := &Shelf{Name: "sci-fi"}
+	if err := ezg.W(shelf).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []Book{{Title: "b"}, {Title: "old", Archived: true}, {Title: "c"}, {Title: "a"}} {
+		b.ShelfID = shelf.ID
+		if err := ezg.W(&b).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ezg.W(&Shelf{}).FindByID(orm, shelf.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := make([]string, 0)
+	for _, b := range got.Books {
+		books = append(books, b.Title)
+	}
+	if !equalStrings(books, []string{"c", "b", "a"}) {
+		t.Fatalf("expected books filtered and ordered by tag, got %v", books)
+	}
+	if len(got.Newest) != 1 || got.Newest[0].Title != "a" {
+		t.Fatalf("expected newest book only, got %+v", got.Newest)
+	}
+}
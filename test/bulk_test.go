@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+// Stamped normalizes its code on insert, which InsertMany has to respect.
+type Stamped struct {
+	gorm.Model
+
+	Code string
+}
+
+var _ ezg.InsertOverrider = (*Stamped)(nil)
+
+func (s *Stamped) Insert(db *gorm.DB) error {
+	s.Code = strings.ToUpper(s.Code)
+	return db.Create(s).Error
+}
+
+func Test_InsertMany(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Task{}, &Author{}, &Post{}, &Img{}, &Vid{}, &Gadget{}, &Stamped{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := make([]Task, 2500)
+	for i := range tasks {
+		tasks[i].Title = fmt.Sprintf("task %d", i)
+	}
+	inserts := 0
+	if err := orm.Callback().Create().After("gorm:create").Register("test:count", func(*gorm.DB) { inserts++ }); err != nil {
+		t.Fatal(err)
+	}
+	progress := make([]string, 0)
+	keys, err := ezg.InsertMany(orm, tasks, &ezg.InsertOptions{BatchSize: 1000, Progress: func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserts != 3 || !equalStrings(progress, []string{"1000/2500", "2000/2500", "2500/2500"}) {
+		t.Fatalf("expected 3 batches, got %d inserts and progress %v", inserts, progress)
+	}
+	if len(keys) != len(tasks) || keys[0] != tasks[0].ID || keys[2499] != tasks[2499].ID || tasks[2499].ID == 0 {
+		t.Fatalf("expected generated keys, got %d keys, first %v", len(keys), keys[0])
+	}
+	found, err := ezg.W(&Task{}).FindByIDs(orm, keys...)
+	if err != nil || len(found) != len(tasks) || found[1234].Title != "task 1234" {
+		t.Fatalf("expected inserted tasks to be found by returned keys, got %d, %v", len(found), err)
+	}
+
+	// associations are omitted unless requested
+	authors := []Author{{Username: "a", Posts: []Post{{Title: "p"}}}}
+	if _, err = ezg.InsertMany(orm, authors, nil); err != nil {
+		t.Fatal(err)
+	}
+	authors = []Author{{Username: "b", Posts: []Post{{Title: "p"}}}}
+	if _, err = ezg.InsertMany(orm, authors, &ezg.InsertOptions{Associations: true}); err != nil {
+		t.Fatal(err)
+	}
+	if posts, err := ezg.W(&Post{}).Find(orm); err != nil || len(posts) != 1 || posts[0].AuthorId != authors[0].ID {
+		t.Fatalf("expected only posts of b to be inserted, got %+v, %v", posts, err)
+	}
+
+	// failing batch rolls back the others
+	gadgets := []Gadget{{Serial: "a", Weight: 1, Label: ptr("a")}, {Serial: "b", Weight: 1, Label: ptr("b")},
+		{Serial: "a", Weight: 1, Label: ptr("c")}}
+	_, err = ezg.InsertMany(orm, gadgets, &ezg.InsertOptions{BatchSize: 2})
+	var qe *ezg.QueryError
+	if !errors.Is(err, ezg.ErrUniqueViolation) || !errors.As(err, &qe) || qe.Op != "InsertMany" {
+		t.Fatalf("expected unique violation of InsertMany, got %v", err)
+	}
+	if n, err := ezg.W(&Gadget{}).Count(orm); err != nil || n != 0 {
+		t.Fatalf("expected no gadgets, got %d, %v", n, err)
+	}
+
+	stamped := []Stamped{{Code: "x"}, {Code: "y"}, {Code: "z"}}
+	calls := 0
+	keys, err = ezg.InsertMany(orm, stamped, &ezg.InsertOptions{BatchSize: 2, Progress: func(int, int) { calls++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[2] != stamped[2].ID || stamped[2].Code != "Z" || calls != 2 {
+		t.Fatalf("expected override to insert every model, got %+v, %v, %d progress calls", stamped, keys, calls)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_ConstraintErrors(t *testing.T) {
+	// foreign keys are not enforced by sqlite unless enabled
+	orm, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared&_foreign_keys=1"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = orm.AutoMigrate(&Gadget{}, &Foo{}, &Bar{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ezg.W(&Gadget{Serial: "a-1", Weight: 1, Label: ptr("a")}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-1", Weight: 1, Label: ptr("b")}).Insert(orm), ezg.ErrUniqueViolation, "", "gadgets", "serial")
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-2", Weight: 1}).Insert(orm), ezg.ErrNotNullViolation, "", "gadgets", "label")
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-3", Weight: -1, Label: ptr("c")}).Insert(orm), ezg.ErrCheckViolation, "weight_positive", "", "")
+	// sqlite does not report which foreign key failed
+	assertConstraint(t, ezg.W(&Bar{Width: 1, FooId: 999}).Insert(orm), ezg.ErrForeignKeyViolation, "", "", "")
+
+	// other errors are not classified
+	_, err = ezg.W(&Gadget{}).FindSql(orm, "no_such_column = ?", 1)
+	ce := &ezg.ConstraintError{}
+	if err == nil || errors.As(err, &ce) {
+		t.Fatalf("expected unclassified error, got %v", err)
+	}
+}
+
+// assertConstraint checks that err is *ezg.ConstraintError of given kind, naming given constraint or table column.
+func assertConstraint(t *testing.T, err error, kind error, constraint, table, column string) {
+	t.Helper()
+	if !errors.Is(err, kind) {
+		t.Fatalf("expected %v, got %v", kind, err)
+	}
+	ce := &ezg.ConstraintError{}
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConstraintError, got %T", err)
+	}
+	if constraint != "" && ce.Constraint != constraint {
+		t.Fatalf("expected constraint %s, got %+v", constraint, ce)
+	}
+	if table != "" && ce.Table != table {
+		t.Fatalf("expected table %s, got %+v", table, ce)
+	}
+	if column != "" && (len(ce.Columns) != 1 || ce.Columns[0] != column) {
+		t.Fatalf("expected column %s, got %+v", column, ce)
+	}
+}
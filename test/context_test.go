@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+type ctxKey struct{}
+
+type CtxNote struct {
+	gorm.Model
+
+	Text  string
+	Marks []CtxMark
+}
+
+type CtxMark struct {
+	gorm.Model
+
+	CtxNoteId uint
+}
+
+var seenPreloadCtx context.Context
+
+func (n *CtxNote) RequiresPreload() (string, func(orm *gorm.DB) *gorm.DB) {
+	return "Marks", func(orm *gorm.DB) *gorm.DB {
+		seenPreloadCtx = orm.Statement.Context
+		return orm
+	}
+}
+
+type CtxOverride struct {
+	gorm.Model
+
+	seen context.Context
+}
+
+func (o *CtxOverride) Count(db *gorm.DB) (uint64, error) {
+	o.seen = db.Statement.Context
+	return 42, nil
+}
+
+func Test_Context(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&CtxNote{}, &CtxMark{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&CtxNote{Text: "hello", Marks: []CtxMark{{}}}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	note, err := ezg.W(&CtxNote{Text: "hello"}).WithContext(ctx).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note == nil || len(note.Marks) != 1 {
+		t.Fatal("note with marks not found")
+	}
+	if seenPreloadCtx == nil || seenPreloadCtx.Value(ctxKey{}) != "value" {
+		t.Fatal("preload did not receive bound context")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ezg.W(&CtxNote{}).WithContext(cancelled).Find(orm)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	err = ezg.W(&CtxNote{Text: "never"}).WithContext(cancelled).Insert(orm)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled on insert, got %v", err)
+	}
+
+	override := &CtxOverride{}
+	cnt, err := ezg.W(override).WithContext(ctx).Count(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 42 || override.seen == nil || override.seen.Value(ctxKey{}) != "value" {
+		t.Fatal("override did not receive bound context")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func Test_AssociationCounts(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Playlist{}, &Track{}, &Editor{}, &Label{}); err != nil {
+		t.Fatal(err)
+	}
+	fans := []Editor{{Name: "ann"}, {Name: "bob"}}
+	for _, p := range []*Playlist{
+		{Name: "rock", Tracks: []Track{{Title: "a"}, {Title: "b"}, {Title: "c"}}, Fans: fans, Tags: []Label{{Name: "loud"}}},
+		{Name: "jazz", Tracks: []Track{{Title: "d"}}, Fans: fans[:1]},
+		{Name: "empty"},
+	} {
+		if err := ezg.W(p).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// tags of other owners, and deleted tracks, are not counted
+	if err := ezg.W(&Label{Name: "other", OwnerID: 1, OwnerType: "others"}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.Delete(&Track{}, "title = ?", "c").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	assertCounts := func(name string, playlists []Playlist) {
+		t.Helper()
+		want := map[string][3]int{"rock": {2, 2, 1}, "jazz": {1, 1, 0}, "empty": {0, 0, 0}}
+		if len(playlists) != len(want) {
+			t.Fatalf("%s: expected %d playlists, got %d", name, len(want), len(playlists))
+		}
+		for _, p := range playlists {
+			if got := [3]int{p.TrackCount, int(p.FanCount), int(p.TagCount)}; got != want[p.Name] || len(p.Tracks) != 0 {
+				t.Fatalf("%s: expected counts %v of %s, got %v", name, want[p.Name], p.Name, got)
+			}
+		}
+	}
+
+	queries := countQueries(t, orm)
+	playlists, err := ezg.W(&Playlist{}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCounts("Find", playlists)
+	if *queries != 4 {
+		t.Fatalf("expected 1 query per association, got %d queries", *queries)
+	}
+	page, err := ezg.W(&Playlist{}).FindPage(orm, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCounts("FindPage", page.Items)
+	keyset, err := ezg.W(&Playlist{}).FindKeyset(orm, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCounts("FindKeyset", keyset.Items)
+
+	one, err := ezg.W(&Playlist{Name: "jazz"}).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one.TrackCount != 1 || one.FanCount != 1 {
+		t.Fatalf("expected counts of jazz, got %+v", one)
+	}
+	if one, err = ezg.W(&Playlist{}).ShallowFindByID(orm, one.ID); err != nil || one.TrackCount != 0 {
+		t.Fatalf("expected shallow finder not to count, got %+v, %v", one, err)
+	}
+
+	counts, err := ezg.W(&Playlist{}).CountAssociation(orm, "Tracks", playlists[0].ID, playlists[1].ID, playlists[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[playlists[0].ID] != 2 || counts[playlists[1].ID] != 1 {
+		t.Fatalf("unexpected counts %v", counts)
+	}
+	if _, err = ezg.W(&Bar{}).CountAssociation(orm, "Foo", 1); !errors.Is(err, ezg.ErrNotCountable) {
+		t.Fatalf("expected ErrNotCountable, got %v", err)
+	}
+	if _, err = ezg.W(&Playlist{}).CountAssociation(orm, "Songs", 1); !errors.Is(err, ezg.ErrUnknownRelation) {
+		t.Fatalf("expected ErrUnknownRelation, got %v", err)
+	}
+}
+
+func Test_AssociationCountsNamingStrategy(t *testing.T) {
+	orm := openSqliteWith(t, &gorm.Config{NamingStrategy: schema.NamingStrategy{TablePrefix: "app_"}})
+	if err := orm.AutoMigrate(&Playlist{}, &Track{}, &Editor{}, &Label{}); err != nil {
+		t.Fatal(err)
+	}
+	p := &Playlist{Name: "rock", Tracks: []Track{{Title: "a"}, {Title: "b"}}, Fans: []Editor{{Name: "ann"}}}
+	if err := ezg.W(p).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	// join table is app_playlist_fans, not playlist_fans of the default naming
+	playlists, err := ezg.W(&Playlist{}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 1 || playlists[0].TrackCount != 2 || playlists[0].FanCount != 1 {
+		t.Fatalf("expected 2 tracks and 1 fan, got %+v", playlists)
+	}
+	n, err := ezg.W(&Playlist{}).CountAssociation(orm, "Fans", p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n[p.ID] != 1 {
+		t.Fatalf("expected 1 fan, got %v", n)
+	}
+}
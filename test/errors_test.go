@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+type Keyless struct {
+	Name string
+}
+
+func Test_Errors(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Task{}, &Keyless{}, &BrokenPreload{}); err != nil {
+		t.Fatal(err)
+	}
+	mkTasks(t, orm)
+
+	// missing records are not errors, unless the wrapper is strict
+	task, err := ezg.W(&Task{}).FindByID(orm, 42)
+	if err != nil || task != nil {
+		t.Fatalf("expected nil task and nil error, got %v, %v", task, err)
+	}
+	_, err = ezg.W(&Task{}).Strict().FindByID(orm, 42)
+	if !errors.Is(err, ezg.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	qe := &ezg.QueryError{}
+	if !errors.As(err, &qe) || qe.Op != "FindByID" || qe.Model != "Task" || !strings.Contains(qe.SQL, "tasks") {
+		t.Fatalf("unexpected query error %+v", qe)
+	}
+	if _, err = ezg.W(&Task{Title: "nope"}).Strict().ShallowFindOne(orm); !errors.Is(err, ezg.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if task, err = ezg.W(&Task{Title: "review"}).Strict().FindOne(orm); err != nil || task.Title != "review" {
+		t.Fatalf("expected review, got %v, %v", task, err)
+	}
+	missing := &Task{}
+	missing.ID = 42
+	if err = ezg.W(missing).Strict().Delete(orm); !errors.Is(err, ezg.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err = ezg.W(missing).Delete(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	// models that can't be handled generically
+	if err = ezg.W(&Task{}).Delete(orm); !errors.Is(err, ezg.ErrNotGormModel) {
+		t.Fatalf("expected ErrNotGormModel, got %v", err)
+	}
+	if err = ezg.W(&Keyless{Name: "x"}).Delete(orm); !errors.Is(err, ezg.ErrNotGormModel) {
+		t.Fatalf("expected ErrNotGormModel, got %v", err)
+	}
+	if _, err = ezg.W(&Keyless{}).FindByID(orm, 1); !errors.Is(err, ezg.ErrNotGormModel) {
+		t.Fatalf("expected ErrNotGormModel, got %v", err)
+	}
+
+	if _, err = ezg.W(&BrokenPreload{}).Find(orm); !errors.Is(err, ezg.ErrInvalidPreload) {
+		t.Fatalf("expected ErrInvalidPreload, got %v", err)
+	}
+
+	// errors of composed operations are reported by the called method
+	_, err = ezg.W(&Task{}).Where(ezg.Eq("nope", 1)).ShallowFindPage(orm, nil, nil, false)
+	if !errors.As(err, &qe) || qe.Op != "ShallowFindPage" || !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// database errors carry the failed statement
+	_, err = ezg.W(&Task{}).FindSql(orm, "no_such_column = ?", 1)
+	if !errors.As(err, &qe) || qe.Op != "FindSql" || !strings.Contains(qe.SQL, "no_such_column = ?") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
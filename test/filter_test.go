@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+func mkTasks(t *testing.T, orm *gorm.DB) {
+	if err := orm.AutoMigrate(&Task{}); err != nil {
+		t.Fatal(err)
+	}
+	note := "with note"
+	tasks := []Task{
+		{Title: "write docs", Priority: 1, Done: true},
+		{Title: "write tests", Priority: 3, Done: false, Note: &note},
+		{Title: "review", Priority: 5, Done: false},
+		{Title: "release", Priority: 0, Done: false},
+	}
+	for i := range tasks {
+		if err := ezg.W(&tasks[i]).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func titles(tasks []Task) []string {
+	out := make([]string, 0, len(tasks))
+	for i := range tasks {
+		out = append(out, tasks[i].Title)
+	}
+	return out
+}
+
+func Test_Filter(t *testing.T) {
+	orm := openSqlite(t)
+	mkTasks(t, orm)
+
+	cases := []struct {
+		name   string
+		filter ezg.Filter
+		want   []string
+	}{
+		{"eq", ezg.Eq("title", "review"), []string{"review"}},
+		{"field name", ezg.Eq("Title", "review"), []string{"review"}},
+		{"neq", ezg.Neq("done", false), []string{"write docs"}},
+		{"lt", ezg.Lt("priority", 1), []string{"release"}},
+		{"gt", ezg.Gt("priority", 3), []string{"review"}},
+		{"in", ezg.In("priority", 0, 5), []string{"review", "release"}},
+		{"in empty", ezg.In("priority"), []string{}},
+		{"in slice", ezg.In("priority", []int{0, 5}), []string{"review", "release"}},
+		{"in slices", ezg.In("priority", []int{1}, [1]uint{3}, 5), []string{"write docs", "write tests", "review"}},
+		{"in empty slice", ezg.In("priority", []int{}), []string{}},
+		{"between", ezg.Between("priority", 1, 3), []string{"write docs", "write tests"}},
+		{"like", ezg.Like("title", "write%"), []string{"write docs", "write tests"}},
+		{"is null", ezg.And(ezg.IsNull("note"), ezg.Eq("done", false)), []string{"review", "release"}},
+		{"not null", ezg.NotNull("note"), []string{"write tests"}},
+		{"or", ezg.Or(ezg.Eq("done", true), ezg.Gte("priority", 5)), []string{"write docs", "review"}},
+		{"nested", ezg.And(
+			ezg.Eq("done", false),
+			ezg.Or(ezg.Like("title", "write%"), ezg.Eq("priority", 0)),
+		), []string{"write tests", "release"}},
+		{"single or", ezg.And(ezg.Eq("done", false), ezg.Or(ezg.Eq("priority", 5))), []string{"review"}},
+		{"not", ezg.Not(ezg.Like("title", "write%")), []string{"review", "release"}},
+		{"not all", ezg.Not(ezg.Like("title", "write%"), ezg.Eq("done", false)),
+			[]string{"write docs", "review", "release"}},
+		{"not all eq", ezg.Not(ezg.Eq("title", "review"), ezg.Eq("priority", 3)),
+			[]string{"write docs", "write tests", "review", "release"}},
+		{"not and", ezg.Not(ezg.And(ezg.Eq("title", "review"), ezg.Eq("priority", 5))),
+			[]string{"write docs", "write tests", "release"}},
+		{"not between", ezg.Not(ezg.Between("priority", 1, 3), ezg.Eq("done", true)),
+			[]string{"write tests", "review", "release"}},
+		{"empty", ezg.Filter{}, []string{"write docs", "write tests", "review", "release"}},
+	}
+	for _, c := range cases {
+		got, err := ezg.W(&Task{}).Where(c.filter).Find(orm)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if gotTitles := titles(got); !equalStrings(gotTitles, c.want) {
+			t.Fatalf("%s: wanted %v, got %v", c.name, c.want, gotTitles)
+		}
+	}
+
+	// filter is joined with struct conditions, custom SQL and used by all finders
+	cnt, err := ezg.W(&Task{Done: true}).Where(ezg.Eq("priority", 1)).Count(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 1 {
+		t.Fatalf("expected 1 task, got %d", cnt)
+	}
+	cnt, err = ezg.W(&Task{}).Where(ezg.Gt("priority", 0)).CountSql(orm, "title LIKE ?", "write%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 2 {
+		t.Fatalf("expected 2 tasks, got %d", cnt)
+	}
+	task, err := ezg.W(&Task{}).Where(ezg.Eq("priority", 5)).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task == nil || task.Title != "review" {
+		t.Fatal("expected review task")
+	}
+	page, err := ezg.W(&Task{}).Where(ezg.Eq("done", false)).FindPaginated(orm, ptr(uint64(1)), ptr(uint64(1)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(page), []string{"review"}) {
+		t.Fatalf("unexpected page %v", titles(page))
+	}
+
+	_, err = ezg.W(&Task{}).Where(ezg.Eq("title; DROP TABLE tasks", "x")).Find(orm)
+	if !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"github.com/m8b-dev/gorm-wrap/ezg"
 	"github.com/m8b-dev/gorm-wrap/test/test_env"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
+	"sync"
 	"testing"
 )
 
@@ -25,7 +28,7 @@ type Bar struct {
 }
 
 func (suite *GormWrapTestSuite) SetupTest() {
-	suite.NoError(suite.DB.AutoMigrate(&Foo{}, &Bar{}))
+	suite.NoError(suite.DB.AutoMigrate(&Foo{}, &Bar{}, &Gadget{}))
 }
 
 func (suite *GormWrapTestSuite) TestJoin() {
@@ -40,6 +43,74 @@ func (suite *GormWrapTestSuite) TestJoin() {
 	suite.NotNil(obj)
 }
 
+func (suite *GormWrapTestSuite) TestConstraintErrors() {
+	t := suite.T()
+	suite.NoError(ezg.W(&Gadget{Serial: "a-1", Weight: 1, Label: ptr("a")}).Insert(suite.DB))
+
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-1", Weight: 1, Label: ptr("b")}).Insert(suite.DB), ezg.ErrUniqueViolation, "idx_gadgets_serial", "gadgets", "serial")
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-2", Weight: 1}).Insert(suite.DB), ezg.ErrNotNullViolation, "", "gadgets", "label")
+	assertConstraint(t, ezg.W(&Gadget{Serial: "a-3", Weight: -1, Label: ptr("c")}).Insert(suite.DB), ezg.ErrCheckViolation, "weight_positive", "gadgets", "")
+	assertConstraint(t, ezg.W(&Bar{Width: 1, FooId: 999}).Insert(suite.DB), ezg.ErrForeignKeyViolation, "fk_bars_foo", "bars", "")
+}
+
 func TestGormWrap(t *testing.T) {
 	suite.Run(t, new(GormWrapTestSuite))
 }
+
+func (suite *GormWrapTestSuite) TestPreloadLimitPerParent() {
+	seedLimitedPreloads(suite.T(), suite.DB)
+
+	authors, err := ezg.W(&Author{}).WithLimit("Posts", 2, ezg.Desc("title")).Find(suite.DB)
+	suite.NoError(err)
+	suite.Len(authors, 2)
+	suite.Equal([]string{"1c", "1b"}, postTitles(authors[0].Posts))
+	suite.Equal([]string{"2c", "2b"}, postTitles(authors[1].Posts))
+}
+
+func (suite *GormWrapTestSuite) TestInTxSerializationRetry() {
+	foo := &Foo{Length: 0}
+	suite.NoError(ezg.W(foo).Insert(suite.DB))
+
+	// concurrent serializable read-modify-write transactions fail with 40001, which is retried
+	const workers = 8
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := ezg.InTx(context.Background(), suite.DB, &ezg.TxOptions{Isolation: sql.LevelSerializable, MaxAttempts: 50},
+				func(tx *gorm.DB) error {
+					f, err := ezg.W(&Foo{}).Strict().FindByID(tx, foo.ID)
+					if err != nil {
+						return err
+					}
+					f.Length++
+					return ezg.W(f).Update(tx)
+				})
+			suite.NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	got, err := ezg.W(&Foo{}).FindByID(suite.DB, foo.ID)
+	suite.NoError(err)
+	suite.Equal(workers, got.Length)
+}
+
+func (suite *GormWrapTestSuite) TestUpsert() {
+	opts := &ezg.UpsertOptions{Index: "idx_gadgets_serial"}
+	gadgets := []Gadget{{Serial: "u-1", Weight: 1, Label: ptr("a")}, {Serial: "u-2", Weight: 1, Label: ptr("b")}}
+	outcomes, err := ezg.UpsertMany(suite.DB, gadgets, opts)
+	suite.NoError(err)
+	suite.Equal([]ezg.UpsertOutcome{ezg.Inserted, ezg.Inserted}, outcomes)
+
+	updated := &Gadget{Serial: "u-2", Weight: 2, Label: ptr("c")}
+	outcome, err := ezg.W(updated).Upsert(suite.DB, opts)
+	suite.NoError(err)
+	suite.Equal(ezg.Updated, outcome)
+	suite.Equal(gadgets[1].ID, updated.ID)
+
+	outcome, err = ezg.W(&Gadget{Serial: "u-1", Weight: 3, Label: ptr("d")}).Upsert(suite.DB, &ezg.UpsertOptions{Columns: []string{"serial"}, DoNothing: true})
+	suite.NoError(err)
+	suite.Equal(ezg.Unchanged, outcome)
+}
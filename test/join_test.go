@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+// countQueries counts queries (including raw rows) executed by orm.
+func countQueries(t *testing.T, orm *gorm.DB) *int {
+	n := new(int)
+	err := orm.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) { *n++ })
+	if err == nil {
+		err = orm.Callback().Row().After("gorm:row").Register("test:count", func(*gorm.DB) { *n++ })
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func Test_JoinedPreload(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Foo{}, &Bar{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := ezg.W(&Bar{Width: i, Foo: Foo{Length: i * 10}}).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ezg.W(&Bar{Width: 4}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	queries := countQueries(t, orm)
+
+	separate, err := ezg.W(&Bar{}).OrderBy(ezg.Asc("width")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *queries != 2 {
+		t.Fatalf("expected 2 queries, got %d", *queries)
+	}
+	*queries = 0
+	joined, err := ezg.W(&Bar{}).Joined("Foo").OrderBy(ezg.Asc("width")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *queries != 1 {
+		t.Fatalf("expected 1 query, got %d", *queries)
+	}
+	if len(joined) != 4 || !reflect.DeepEqual(separate, joined) {
+		t.Fatalf("expected identical results, got\n%+v\n%+v", separate, joined)
+	}
+
+	one, err := ezg.W(&Bar{}).Joined("Foo").FindByID(orm, separate[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*one, separate[1]) {
+		t.Fatalf("expected %+v, got %+v", separate[1], one)
+	}
+	page, err := ezg.W(&Bar{}).Joined("Foo").FindPage(orm, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || !reflect.DeepEqual(page.Items, separate) {
+		t.Fatalf("expected identical page, got %+v", page)
+	}
+}
+
+func Test_JoinedPreloadTag(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Editor{}, &Label{}, &Ticket{}); err != nil {
+		t.Fatal(err)
+	}
+	ticket := &Ticket{Title: "reviewed", Assignee: Editor{Name: "ann"}, Reviewer: &Editor{Name: "bob"},
+		Labels: []Label{{Name: "bug"}, {Name: "ui"}}}
+	if err := ezg.W(ticket).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&Ticket{Title: "open", Assignee: Editor{Name: "cid"}}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+	queries := countQueries(t, orm)
+
+	joined, err := ezg.W(&Ticket{}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// labels are has-many, preloaded by separate query despite the tag
+	if *queries != 2 {
+		t.Fatalf("expected 2 queries, got %d", *queries)
+	}
+	separate, err := ezg.W(&Ticket{}).PreloadOnly("Assignee", "Reviewer", "Labels").Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(joined) != 2 || !reflect.DeepEqual(separate, joined) {
+		t.Fatalf("expected identical results, got\n%+v\n%+v", separate, joined)
+	}
+	if joined[0].Reviewer == nil || joined[0].Reviewer.Name != "bob" || len(joined[0].Labels) != 2 ||
+		joined[1].Reviewer != nil || joined[1].Assignee.Name != "cid" {
+		t.Fatalf("unexpected tickets %+v", joined)
+	}
+
+	// joined tables share columns such as id, so custom SQL qualifies them
+	got, err := ezg.W(&Ticket{}).FindOneSql(orm, "tickets.id = ?", joined[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Assignee.Name != "cid" {
+		t.Fatalf("unexpected ticket %+v", got)
+	}
+}
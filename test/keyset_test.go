@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_Keyset(t *testing.T) {
+	orm := openSqlite(t)
+	mkTasks(t, orm)
+	for _, title := range []string{"triage", "plan", "deploy", "celebrate"} {
+		if err := ezg.W(&Task{Title: title, Priority: 3}).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := ezg.W(&Task{}).OrderBy(ezg.Desc("priority"))
+	all, err := q.OrderBy(ezg.Asc("id")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// forward
+	pages := make([]ezg.KeysetPage[Task], 0)
+	got := make([]Task, 0)
+	cursor := ""
+	for {
+		page, err := q.FindKeyset(orm, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+		got = append(got, page.Items...)
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	if len(pages) != 3 || pages[0].Prev != "" || pages[1].Prev == "" {
+		t.Fatalf("unexpected pagination %d pages", len(pages))
+	}
+	if !equalStrings(titles(got), titles(all)) {
+		t.Fatalf("wanted %v, got %v", titles(all), titles(got))
+	}
+
+	// backward from the last page
+	back, err := q.FindKeyset(orm, pages[2].Prev, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(back.Items), titles(pages[1].Items)) || back.Next == "" || back.Prev == "" {
+		t.Fatalf("wanted %v, got %v", titles(pages[1].Items), titles(back.Items))
+	}
+	back, err = q.FindKeyset(orm, back.Prev, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(back.Items), titles(pages[0].Items)) || back.Prev != "" {
+		t.Fatalf("wanted %v, got %v", titles(pages[0].Items), titles(back.Items))
+	}
+
+	// sql and struct filters
+	page, err := q.FindKeysetSql(orm, "", 10, "title LIKE ?", "write%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(page.Items), []string{"write tests", "write docs"}) || page.Next != "" {
+		t.Fatalf("unexpected page %v", titles(page.Items))
+	}
+	page, err = ezg.W(&Task{Priority: 3}).FindKeyset(orm, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = ezg.W(&Task{Priority: 3}).FindKeyset(orm, page.Next, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(page.Items), []string{"plan", "deploy"}) {
+		t.Fatalf("unexpected page %v", titles(page.Items))
+	}
+
+	// cursor is bound to conditions of the query it was issued for
+	if _, err = ezg.W(&Task{Priority: 5}).FindKeyset(orm, page.Next, 2); !errors.Is(err, ezg.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for other model conditions, got %v", err)
+	}
+	if _, err = ezg.W(&Task{Priority: 3}).Where(ezg.Eq("done", false)).FindKeyset(orm, page.Next, 2); !errors.Is(err, ezg.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for other filters, got %v", err)
+	}
+	page, err = q.FindKeysetSql(orm, "", 1, "title LIKE ?", "write%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = q.FindKeysetSql(orm, page.Next, 1, "title LIKE ?", "re%"); !errors.Is(err, ezg.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for other sql arguments, got %v", err)
+	}
+	if page, err = q.FindKeysetSql(orm, page.Next, 1, "title LIKE ?", "write%"); err != nil || len(page.Items) != 1 {
+		t.Fatalf("expected second page of the same sql, got %v, %v", titles(page.Items), err)
+	}
+
+	// limit is bounded, instead of overflowing
+	page, err = q.FindKeyset(orm, "", ^uint64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != len(all) || page.Next != "" {
+		t.Fatalf("expected all %d tasks on single page, got %d", len(all), len(page.Items))
+	}
+
+	// tampering and reuse with other ordering
+	data, sig, _ := strings.Cut(pages[0].Next, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := map[string]interface{}{}
+	if err = json.Unmarshal(raw, &payload); err != nil {
+		t.Fatal(err)
+	}
+	payload["b"] = true
+	if raw, err = json.Marshal(payload); err != nil {
+		t.Fatal(err)
+	}
+	tampered := base64.RawURLEncoding.EncodeToString(raw) + "." + sig
+	if _, err = q.FindKeyset(orm, tampered, 3); !errors.Is(err, ezg.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+	if _, err = ezg.W(&Task{}).FindKeyset(orm, pages[0].Next, 3); !errors.Is(err, ezg.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func Test_KeysetPreload(t *testing.T) {
+	orm := openSqlite(t)
+	mkFakeDB(t, orm)
+	for _, name := range []string{"a", "b", "c"} {
+		err := ezg.W(&Author{Username: name, Posts: []Post{{Title: name, Images: []Img{{Title: name}}}}}).Insert(orm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := ezg.W(&Author{}).FindKeyset(orm, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err = ezg.W(&Author{}).FindKeyset(orm, page.Next, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || len(page.Items[0].Posts) != 1 || len(page.Items[0].Posts[0].Images) != 1 {
+		t.Fatal("keyset page not preloaded")
+	}
+	page, err = ezg.W(&Author{}).ShallowFindKeyset(orm, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].Posts != nil {
+		t.Fatal("shallow keyset page preloaded")
+	}
+}
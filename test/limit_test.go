@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// seedLimitedPreloads creates two shelves and two authors, each with three books or posts, titled in order of
+// insertion: 1a, 1b, 1c, 2a, 2b, 2c.
+func seedLimitedPreloads(t *testing.T, orm *gorm.DB) {
+	if err := orm.AutoMigrate(&Shelf{}, &Book{}, &Author{}, &Post{}, &Img{}, &Vid{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{"1", "2"} {
+		author := &Author{Username: prefix}
+		shelf := &Shelf{Name: prefix}
+		for _, suffix := range []string{"a", "b", "c"} {
+			author.Posts = append(author.Posts, Post{Title: prefix + suffix})
+			shelf.Books = append(shelf.Books, Book{Title: prefix + suffix})
+		}
+		if err := ezg.W(author).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ezg.W(shelf).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func postTitles(posts []Post) []string {
+	out := make([]string, 0, len(posts))
+	for _, p := range posts {
+		out = append(out, p.Title)
+	}
+	return out
+}
+
+func Test_PreloadLimitPerParent(t *testing.T) {
+	orm := openSqlite(t)
+	seedLimitedPreloads(t, orm)
+
+	shelves, err := ezg.W(&Shelf{}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shelves) != 2 {
+		t.Fatalf("expected 2 shelves, got %d", len(shelves))
+	}
+	for i, want := range []string{"1c", "2c"} {
+		if len(shelves[i].Newest) != 1 || shelves[i].Newest[0].Title != want {
+			t.Fatalf("expected newest book %s of shelf %s, got %+v", want, shelves[i].Name, shelves[i].Newest)
+		}
+	}
+
+	authors, err := ezg.W(&Author{}).WithLimit("Posts", 2, ezg.Desc("title")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 2 || !equalStrings(postTitles(authors[0].Posts), []string{"1c", "1b"}) ||
+		!equalStrings(postTitles(authors[1].Posts), []string{"2c", "2b"}) {
+		t.Fatalf("expected 2 latest posts per author, got %+v", authors)
+	}
+
+	// default order is primary key, soft deleted posts are not counted
+	if err = orm.Delete(&Post{}, "title = ?", "1a").Error; err != nil {
+		t.Fatal(err)
+	}
+	page, err := ezg.W(&Author{}).PreloadOnly().WithLimit("Posts", 2).FindPage(orm, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || !equalStrings(postTitles(page.Items[0].Posts), []string{"1b", "1c"}) ||
+		!equalStrings(postTitles(page.Items[1].Posts), []string{"2a", "2b"}) {
+		t.Fatalf("expected 2 first posts per author, got %+v", page.Items)
+	}
+
+	if _, err = ezg.W(&Author{}).WithLimit("Posts", 2, ezg.Desc("nope")).Find(orm); !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+	if _, err = ezg.W(&Author{}).WithLimit("Posts", 0).Find(orm); !errors.Is(err, ezg.ErrInvalidPreload) {
+		t.Fatalf("expected ErrInvalidPreload, got %v", err)
+	}
+	if _, err = ezg.W(&Bar{}).WithLimit("Foo", 1).Find(orm); !errors.Is(err, ezg.ErrInvalidPreload) {
+		t.Fatalf("expected ErrInvalidPreload, got %v", err)
+	}
+}
+
+func Test_PreloadLimitNamingStrategy(t *testing.T) {
+	orm := openSqliteWith(t, &gorm.Config{NamingStrategy: schema.NamingStrategy{NoLowerCase: true}})
+	seedLimitedPreloads(t, orm)
+
+	// partition column is ShelfID, not shelf_id of the default naming
+	shelves, err := ezg.W(&Shelf{}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shelves) != 2 || len(shelves[0].Newest) != 1 || shelves[0].Newest[0].Title != "1c" {
+		t.Fatalf("expected newest book per shelf, got %+v", shelves)
+	}
+
+	authors, err := ezg.W(&Author{}).WithLimit("Posts", 1, ezg.Desc("Title")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 2 || !equalStrings(postTitles(authors[0].Posts), []string{"1c"}) ||
+		!equalStrings(postTitles(authors[1].Posts), []string{"2c"}) {
+		t.Fatalf("expected latest post per author, got %+v", authors)
+	}
+}
@@ -191,3 +191,17 @@ func mkFakeDB(t *testing.T, orm *gorm.DB) {
 func ptr[T any](val T) *T {
 	return &val
 }
+
+// openSqlite opens a private in-memory sqlite database named after the running test.
+func openSqlite(t *testing.T) *gorm.DB {
+	return openSqliteWith(t, &gorm.Config{})
+}
+
+// openSqliteWith opens in-memory database of the test with config, such as custom naming strategy.
+func openSqliteWith(t *testing.T, config *gorm.Config) *gorm.DB {
+	orm, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orm
+}
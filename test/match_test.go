@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_MatchZeroValues(t *testing.T) {
+	orm := openSqlite(t)
+	mkTasks(t, orm)
+
+	// without Match, zero values are ignored
+	all, err := ezg.W(&Task{Done: false}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Fatalf("expected all 4 tasks, got %d", len(all))
+	}
+
+	// bool
+	open, err := ezg.W(&Task{Done: false}).Match("Done").Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(open), []string{"write tests", "review", "release"}) {
+		t.Fatalf("unexpected tasks %v", titles(open))
+	}
+
+	// int, by column name, combined with non-zero field
+	task, err := ezg.W(&Task{Priority: 0, Title: "release"}).Match("priority").FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task == nil || task.Title != "release" {
+		t.Fatal("expected release task")
+	}
+	cnt, err := ezg.W(&Task{Priority: 0}).Match("Priority").Count(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 1 {
+		t.Fatalf("expected 1 task, got %d", cnt)
+	}
+
+	// string
+	cnt, err = ezg.W(&Task{Title: ""}).Match("Title").Count(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 0 {
+		t.Fatalf("expected 0 tasks, got %d", cnt)
+	}
+
+	// nil pointer is matched as NULL
+	page, err := ezg.W(&Task{}).Match("Done", "Note").FindPaginated(orm, nil, ptr(uint64(10)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(page), []string{"release", "review"}) {
+		t.Fatalf("unexpected tasks %v", titles(page))
+	}
+
+	_, err = ezg.W(&Task{}).Match("Missing").Find(orm)
+	if !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+}
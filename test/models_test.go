@@ -35,3 +35,120 @@ type Vid struct {
 
 	PostId uint
 }
+
+type Task struct {
+	gorm.Model
+
+	Title    string
+	Priority int
+	Done     bool
+	Note     *string
+}
+
+type BrokenPreload struct {
+	gorm.Model
+
+	Name string
+}
+
+func (b *BrokenPreload) RequiresPreload() ([]string, []func(orm *gorm.DB) *gorm.DB) {
+	return []string{"Foo", "Bar"}, []func(orm *gorm.DB) *gorm.DB{nil}
+}
+
+type Gadget struct {
+	gorm.Model
+
+	Serial string  `gorm:"uniqueIndex"`
+	Weight int     `gorm:"check:weight_positive,weight > 0"`
+	Label  *string `gorm:"not null"`
+}
+
+type Category struct {
+	gorm.Model
+
+	Name     string
+	ParentID *uint
+	Children []Category `gorm:"foreignKey:ParentID" ezg:"preload-depth=2"`
+}
+
+type Editor struct {
+	gorm.Model
+
+	Name string
+}
+
+type Audit struct {
+	OwnerID uint
+	Owner   Editor
+}
+
+type DocMeta struct {
+	ReviewerID *uint
+	Reviewer   *Editor
+}
+
+type Label struct {
+	gorm.Model
+
+	Name      string
+	OwnerID   uint
+	OwnerType string
+}
+
+type Doc struct {
+	gorm.Model
+	Audit
+
+	Meta     DocMeta  `gorm:"embedded;embeddedPrefix:meta_"`
+	Labels   []Label  `gorm:"polymorphic:Owner"`
+	Watchers []Editor `gorm:"many2many:doc_watchers"`
+	Drafts   []Editor `gorm:"-"`
+	Keywords []string `gorm:"serializer:json"`
+	Blob     []byte
+}
+
+type Shelf struct {
+	gorm.Model
+
+	Name   string
+	Books  []Book `ezg:"preload,where=archived = false,order=title desc"`
+	Newest []Book `gorm:"foreignKey:ShelfID" ezg:"order=id desc,limit=1"`
+}
+
+type Book struct {
+	gorm.Model
+
+	ShelfID  uint
+	Title    string
+	Archived bool
+}
+
+type Ticket struct {
+	gorm.Model
+
+	Title      string
+	AssigneeID uint
+	Assignee   Editor `ezg:"join"`
+	ReviewerID *uint
+	Reviewer   *Editor `ezg:"join"`
+	Labels     []Label `gorm:"polymorphic:Owner" ezg:"join"`
+}
+
+type Playlist struct {
+	gorm.Model
+
+	Name       string
+	Tracks     []Track  `ezg:"no-preload"`
+	Fans       []Editor `gorm:"many2many:playlist_fans" ezg:"no-preload"`
+	Tags       []Label  `gorm:"polymorphic:Owner" ezg:"no-preload"`
+	TrackCount int      `gorm:"-" ezg:"count=Tracks"`
+	FanCount   int64    `gorm:"-" ezg:"count=Fans"`
+	TagCount   uint     `gorm:"-" ezg:"count=Tags"`
+}
+
+type Track struct {
+	gorm.Model
+
+	PlaylistID uint
+	Title      string
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+type Country struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+func Test_Order(t *testing.T) {
+	orm := openSqlite(t)
+	mkTasks(t, orm)
+
+	tasks, err := ezg.W(&Task{}).OrderBy(ezg.Desc("priority")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(tasks), []string{"review", "write tests", "write docs", "release"}) {
+		t.Fatalf("unexpected order %v", titles(tasks))
+	}
+
+	tasks, err = ezg.W(&Task{}).OrderBy(ezg.Asc("Done"), ezg.Desc("title")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(tasks), []string{"write tests", "review", "release", "write docs"}) {
+		t.Fatalf("unexpected order %v", titles(tasks))
+	}
+
+	tasks, err = ezg.W(&Task{}).OrderBy(ezg.Asc("note").NullsFirst(), ezg.Asc("id")).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if titles(tasks)[3] != "write tests" {
+		t.Fatalf("expected non-null note last, got %v", titles(tasks))
+	}
+	tasks, err = ezg.W(&Task{}).OrderBy(ezg.Asc("note").NullsLast(), ezg.Asc("id")).FindSql(orm, "priority > ?", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(tasks), []string{"write tests", "write docs", "review"}) {
+		t.Fatalf("unexpected order %v", titles(tasks))
+	}
+
+	// reverse flips every term, including NULL placement
+	tasks, err = ezg.W(&Task{}).OrderBy(ezg.ParseOrder("note, -priority")...).FindPaginated(orm, nil, ptr(uint64(2)), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(tasks), []string{"write tests", "release"}) {
+		t.Fatalf("unexpected order %v", titles(tasks))
+	}
+
+	task, err := ezg.W(&Task{}).OrderBy(ezg.Desc("priority")).FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task == nil || task.Title != "review" {
+		t.Fatal("expected highest priority task")
+	}
+
+	_, err = ezg.W(&Task{}).OrderBy(ezg.ParseOrder("-priority,id;DELETE FROM tasks")...).Find(orm)
+	if !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+
+	// models without id column are ordered by their primary key
+	if err = orm.AutoMigrate(&Country{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Country{{Code: "PL", Name: "Poland"}, {Code: "DE", Name: "Germany"}} {
+		if err = ezg.W(&c).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countries, err := ezg.W(&Country{}).FindPaginated(orm, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(countries) != 2 || countries[0].Code != "DE" {
+		t.Fatalf("unexpected countries %v", countries)
+	}
+}
+
+func Test_ParseOrder(t *testing.T) {
+	got := ezg.ParseOrder(" -created_at, +name,,title ,-")
+	want := []ezg.Order{ezg.Desc("created_at"), ezg.Asc("name"), ezg.Asc("title")}
+	if len(got) != len(want) {
+		t.Fatalf("wanted %d orders, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("wanted %v at index %d, got %v", want[i], i, got[i])
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+type Overridden struct {
+	gorm.Model
+
+	calls []bool
+}
+
+var (
+	_ ezg.FindOverrider[Overridden]             = (*Overridden)(nil)
+	_ ezg.FindOneSqlOverrider[Overridden]       = (*Overridden)(nil)
+	_ ezg.FindPaginatedSqlOverrider[Overridden] = (*Overridden)(nil)
+)
+
+func (o *Overridden) Find(db *gorm.DB, shallow bool) ([]Overridden, error) {
+	o.calls = append(o.calls, shallow)
+	return []Overridden{}, nil
+}
+
+func (o *Overridden) FindOneSql(db *gorm.DB, shallow bool, sql string, sqlArgs ...interface{}) (*Overridden, error) {
+	o.calls = append(o.calls, shallow)
+	return o, nil
+}
+
+func (o *Overridden) FindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, shallow bool, sql string, sqlArgs ...interface{}) ([]Overridden, error) {
+	o.calls = append(o.calls, shallow)
+	return []Overridden{}, nil
+}
+
+type LegacyOverride struct {
+	gorm.Model
+}
+
+// Methods below have signatures of the overrides before shallow parameter was added, so they are not overrides.
+func (l *LegacyOverride) Find(db *gorm.DB) ([]LegacyOverride, error) {
+	return nil, nil
+}
+
+func (l *LegacyOverride) FindOneSql(db *gorm.DB, sql string, sqlArgs ...interface{}) (*LegacyOverride, error) {
+	return nil, nil
+}
+
+func (l *LegacyOverride) FindPaginatedSql(db *gorm.DB, offset *uint64, limit *uint64, reverseOrder bool, sql string, sqlArgs ...interface{}) ([]LegacyOverride, error) {
+	return nil, nil
+}
+
+func Test_Overrides(t *testing.T) {
+	orm := openSqlite(t)
+	o := &Overridden{}
+	if _, err := ezg.W(o).Find(orm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ezg.W(o).ShallowFind(orm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ezg.W(o).ShallowFindOneSql(orm, "id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ezg.W(o).FindPaginatedSql(orm, nil, nil, false, "id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.calls) != 4 || o.calls[0] || !o.calls[1] || !o.calls[2] || o.calls[3] {
+		t.Fatalf("unexpected override calls %v", o.calls)
+	}
+
+	if err := ezg.Validate(&Overridden{}); err != nil {
+		t.Fatal(err)
+	}
+	err := ezg.Validate(LegacyOverride{})
+	if !errors.Is(err, ezg.ErrOverrideSignature) {
+		t.Fatalf("expected ErrOverrideSignature, got %v", err)
+	}
+	if n := strings.Count(err.Error(), "before shallow parameter was added"); n != 3 {
+		t.Fatalf("expected 3 methods with legacy signature, got %d in %v", n, err)
+	}
+}
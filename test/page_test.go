@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_Page(t *testing.T) {
+	orm := openSqlite(t)
+	mkTasks(t, orm)
+
+	for _, q := range []ezg.Q[Task]{ezg.W(&Task{}).Match("Done"), ezg.W(&Task{}).Match("Done").WindowCount()} {
+		page, err := q.FindPage(orm, ptr(uint64(1)), ptr(uint64(1)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalStrings(titles(page.Items), []string{"review"}) || page.Total != 3 || page.Offset != 1 || page.Limit != 1 {
+			t.Fatalf("unexpected page %+v", page)
+		}
+		if !page.HasNext || !page.HasPrev {
+			t.Fatalf("unexpected page flags %+v", page)
+		}
+
+		page, err = q.FindPage(orm, nil, ptr(uint64(2)), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalStrings(titles(page.Items), []string{"release", "review"}) || page.Total != 3 || page.HasPrev || !page.HasNext {
+			t.Fatalf("unexpected page %+v", page)
+		}
+
+		// past the end, total is still known
+		page, err = q.FindPage(orm, ptr(uint64(10)), ptr(uint64(2)), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != 0 || page.Total != 3 || page.HasNext || !page.HasPrev {
+			t.Fatalf("unexpected page %+v", page)
+		}
+
+		page, err = q.Where(ezg.Gt("priority", 0)).FindPageSql(orm, nil, nil, false, "title LIKE ?", "write%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalStrings(titles(page.Items), []string{"write docs", "write tests"}) || page.Total != 2 || page.HasNext {
+			t.Fatalf("unexpected page %+v", page)
+		}
+	}
+}
+
+func Test_PageWindowPreload(t *testing.T) {
+	orm := openSqlite(t)
+	mkFakeDB(t, orm)
+	for _, name := range []string{"a", "b", "c"} {
+		err := ezg.W(&Author{Username: name, Posts: []Post{{Title: name, Images: []Img{{Title: name}}}}}).Insert(orm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := ezg.W(&Author{}).WindowCount().FindPage(orm, ptr(uint64(1)), ptr(uint64(1)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Items) != 1 || page.Items[0].Username != "b" {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if len(page.Items[0].Posts) != 1 || len(page.Items[0].Posts[0].Images) != 1 {
+		t.Fatal("page not preloaded")
+	}
+	page, err = ezg.W(&Author{}).WindowCount().ShallowFindPage(orm, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Items[0].Posts != nil {
+		t.Fatalf("unexpected shallow page %+v", page)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_PreloadErrors(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&BrokenPreload{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&BrokenPreload{Name: "broken"}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ezg.W(&BrokenPreload{}).FindOne(orm)
+	if !errors.Is(err, ezg.ErrPreloadMismatch) {
+		t.Fatalf("expected ErrPreloadMismatch, got %v", err)
+	}
+	_, err = ezg.W(&BrokenPreload{}).FindPaginated(orm, nil, nil, false)
+	if !errors.Is(err, ezg.ErrPreloadMismatch) {
+		t.Fatalf("expected ErrPreloadMismatch, got %v", err)
+	}
+
+	// shallow reads do not preload, so they are not affected
+	rows, err := ezg.W(&BrokenPreload{}).ShallowFind(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatal("row not found")
+	}
+
+	if err = ezg.Validate(&Author{}, &Post{}, &BrokenPreload{}); !errors.Is(err, ezg.ErrPreloadMismatch) {
+		t.Fatalf("expected ErrPreloadMismatch, got %v", err)
+	}
+}
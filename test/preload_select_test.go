@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_PreloadSelection(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Author{}, &Post{}, &Img{}, &Vid{}, &Category{}); err != nil {
+		t.Fatal(err)
+	}
+	author := &Author{Username: "writer", Posts: []Post{{
+		Title:  "post",
+		Images: []Img{{Title: "img"}},
+		Videos: []Vid{{Title: "vid"}},
+	}}}
+	if err := ezg.W(author).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ezg.W(&Author{}).Without("Posts.Images").FindByID(orm, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Posts) != 1 || len(got.Posts[0].Images) != 0 || len(got.Posts[0].Videos) != 1 {
+		t.Fatalf("expected posts with videos only, got %+v", got.Posts)
+	}
+
+	got, err = ezg.W(&Author{}).PreloadOnly("Posts").FindByID(orm, author.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Posts) != 1 || len(got.Posts[0].Images) != 0 || len(got.Posts[0].Videos) != 0 {
+		t.Fatalf("expected posts only, got %+v", got.Posts)
+	}
+
+	got, err = ezg.W(&Author{}).PreloadOnly().With("Posts.Images").FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Posts) != 1 || len(got.Posts[0].Images) != 1 || len(got.Posts[0].Videos) != 0 {
+		t.Fatalf("expected posts with images only, got %+v", got.Posts)
+	}
+
+	authors, err := ezg.W(&Author{}).Without("Posts").Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 1 || len(authors[0].Posts) != 0 {
+		t.Fatalf("expected no posts, got %+v", authors)
+	}
+
+	// With extends past preload-depth of the model
+	parent := (*uint)(nil)
+	for _, name := range []string{"root", "a", "b", "c"} {
+		c := &Category{Name: name, ParentID: parent}
+		if err = ezg.W(c).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+		parent = &c.ID
+	}
+	root, err := ezg.W(&Category{Name: "root"}).With("Children.Children.Children").FindOne(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 1 || len(root.Children[0].Children) != 1 || len(root.Children[0].Children[0].Children) != 1 {
+		t.Fatalf("expected three levels of children, got %+v", root.Children)
+	}
+
+	if _, err = ezg.W(&Author{}).With("Posts.Comments").Find(orm); !errors.Is(err, ezg.ErrUnknownRelation) {
+		t.Fatalf("expected ErrUnknownRelation, got %v", err)
+	}
+	if _, err = ezg.W(&Author{}).Without("Username").FindPage(orm, nil, nil, false); !errors.Is(err, ezg.ErrUnknownRelation) {
+		t.Fatalf("expected ErrUnknownRelation, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+type Token struct {
+	UUID  string `gorm:"primaryKey;type:uuid"`
+	Owner string
+}
+
+type Membership struct {
+	UserId  uint `gorm:"primaryKey;autoIncrement:false"`
+	GroupId uint `gorm:"primaryKey;autoIncrement:false"`
+	Role    string
+}
+
+func Test_PrimaryKeys(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Token{}, &Membership{}, &Task{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uuid := range []string{"7c3c2a3e-0000-4000-8000-000000000001", "7c3c2a3e-0000-4000-8000-000000000002"} {
+		if err := ezg.W(&Token{UUID: uuid, Owner: "me"}).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []Membership{{1, 1, "owner"}, {1, 2, "member"}, {2, 1, "member"}} {
+		if err := ezg.W(&m).Insert(orm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// uuid
+	token, err := ezg.W(&Token{}).FindByID(orm, "7c3c2a3e-0000-4000-8000-000000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.UUID != "7c3c2a3e-0000-4000-8000-000000000002" {
+		t.Fatal("token not found")
+	}
+	if err = ezg.W(token).Delete(orm); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := ezg.W(&Token{Owner: "me"}).Find(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].UUID != "7c3c2a3e-0000-4000-8000-000000000001" {
+		t.Fatalf("unexpected tokens %v", tokens)
+	}
+
+	// composite
+	member, err := ezg.W(&Membership{}).FindByID(orm, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member == nil || member.Role != "member" {
+		t.Fatal("membership not found")
+	}
+	members, err := ezg.W(&Membership{}).FindByIDs(orm, []interface{}{2, 1}, []interface{}{1, 1}, []interface{}{3, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].UserId != 1 || members[1].UserId != 2 {
+		t.Fatalf("unexpected memberships %v", members)
+	}
+	if err = ezg.W(&Membership{UserId: 1, GroupId: 1}).Delete(orm); err != nil {
+		t.Fatal(err)
+	}
+	cnt, err := ezg.W(&Membership{}).Count(orm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 2 {
+		t.Fatalf("expected 2 memberships, got %d", cnt)
+	}
+	if _, err = ezg.W(&Membership{}).FindByIDs(orm, 1, 2); err == nil {
+		t.Fatal("expected error for scalar composite keys")
+	}
+
+	// gorm.Model, missing records and filters
+	mkTasks(t, orm)
+	task, err := ezg.W(&Task{}).FindByID(orm, uint(99))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task != nil {
+		t.Fatal("expected nil task")
+	}
+	tasks, err := ezg.W(&Task{}).Where(ezg.Eq("done", false)).FindByIDs(orm, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(titles(tasks), []string{"write tests", "review"}) {
+		t.Fatalf("unexpected tasks %v", titles(tasks))
+	}
+	if err = ezg.W(&Task{}).Delete(orm); err == nil {
+		t.Fatal("expected error deleting model without primary key set")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var noBackoff = func(int) time.Duration { return 0 }
+
+func Test_InTx(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Task{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.Exec("DELETE FROM tasks").Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	titles := func() []string {
+		tasks, err := ezg.W(&Task{}).Find(orm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]string, 0)
+		for _, task := range tasks {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	// conflicts are retried, every attempt in new transaction
+	attempts := 0
+	err := ezg.InTx(ctx, orm, &ezg.TxOptions{Backoff: noBackoff}, func(tx *gorm.DB) error {
+		attempts++
+		if err := ezg.W(&Task{Title: "retried"}).Insert(tx); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 || !equalStrings(titles(), []string{"retried"}) {
+		t.Fatalf("expected single task after 3 attempts, got %d attempts, %v, %v", attempts, titles(), err)
+	}
+
+	attempts = 0
+	err = ezg.InTx(ctx, orm, &ezg.TxOptions{Isolation: sql.LevelSerializable}, func(tx *gorm.DB) error {
+		attempts++
+		return ezg.W(&Task{Title: "committed"}).Insert(tx)
+	})
+	if err != nil || attempts != 1 {
+		t.Fatalf("expected single attempt, got %d, %v", attempts, err)
+	}
+
+	attempts = 0
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	err = ezg.InTx(ctx, orm, &ezg.TxOptions{MaxAttempts: 2, Backoff: noBackoff}, func(tx *gorm.DB) error {
+		attempts++
+		if err := ezg.W(&Task{Title: "exhausted"}).Insert(tx); err != nil {
+			return err
+		}
+		return deadlock
+	})
+	if !errors.Is(err, deadlock) || attempts != 2 {
+		t.Fatalf("expected deadlock after 2 attempts, got %d, %v", attempts, err)
+	}
+
+	attempts = 0
+	failure := errors.New("validation failed")
+	err = ezg.InTx(ctx, orm, nil, func(tx *gorm.DB) error {
+		attempts++
+		return failure
+	})
+	if !errors.Is(err, failure) || attempts != 1 {
+		t.Fatalf("expected failure without retry, got %d, %v", attempts, err)
+	}
+
+	// nested call runs in savepoint, which is rolled back alone
+	err = ezg.InTx(ctx, orm, nil, func(tx *gorm.DB) error {
+		if err := ezg.W(&Task{Title: "outer"}).Insert(tx); err != nil {
+			return err
+		}
+		inner := ezg.InTx(ctx, tx, &ezg.TxOptions{Backoff: noBackoff}, func(tx *gorm.DB) error {
+			attempts++
+			if err := ezg.W(&Task{Title: "inner"}).Insert(tx); err != nil {
+				return err
+			}
+			return &pgconn.PgError{Code: "40001"}
+		})
+		if inner == nil {
+			t.Error("expected inner transaction to fail")
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 || !equalStrings(titles(), []string{"retried", "committed", "outer"}) {
+		t.Fatalf("expected inner transaction rolled back without retry, got %d attempts, %v, %v", attempts, titles(), err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	err = ezg.InTx(cancelled, orm, &ezg.TxOptions{Backoff: func(int) time.Duration {
+		cancel()
+		return time.Hour
+	}}, func(tx *gorm.DB) error {
+		return deadlock
+	})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, deadlock) {
+		t.Fatalf("expected cancellation during backoff, got %v", err)
+	}
+}
+
+func Test_InTxConcurrent(t *testing.T) {
+	// file database, as every connection to in-memory one gets its own database
+	orm, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tx.db")+"?_busy_timeout=50"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = orm.AutoMigrate(&Task{}); err != nil {
+		t.Fatal(err)
+	}
+	counter := &Task{Title: "counter"}
+	if err = ezg.W(counter).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	// every worker reads the counter and writes it incremented, so that concurrent transactions conflict
+	const workers = 8
+	increment := func(opts *ezg.TxOptions) error {
+		return ezg.InTx(context.Background(), orm, opts, func(tx *gorm.DB) error {
+			task, err := ezg.W(&Task{}).Strict().FindByID(tx, counter.ID)
+			if err != nil {
+				return err
+			}
+			time.Sleep(5 * time.Millisecond)
+			task.Priority++
+			return ezg.W(task).Update(tx)
+		})
+	}
+	run := func(opts *ezg.TxOptions) (failed int) {
+		wg, mu := sync.WaitGroup{}, sync.Mutex{}
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := increment(opts); err != nil {
+					if !ezg.Retryable(err) {
+						t.Errorf("unexpected error %v", err)
+					}
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		return failed
+	}
+	priority := func() int {
+		task, err := ezg.W(&Task{}).FindByID(orm, counter.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.Priority
+	}
+
+	if failed := run(&ezg.TxOptions{MaxAttempts: 1}); failed == 0 || priority() != workers-failed {
+		t.Fatalf("expected conflicts without retries, got %d failures and counter %d", failed, priority())
+	}
+	before := priority()
+	if failed := run(&ezg.TxOptions{MaxAttempts: 100}); failed != 0 || priority() != before+workers {
+		t.Fatalf("expected every increment to succeed with retries, got %d failures and counter %d", failed, priority()-before)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+)
+
+func Test_Upsert(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Gadget{}); err != nil {
+		t.Fatal(err)
+	}
+	bySerial := &ezg.UpsertOptions{Columns: []string{"Serial"}}
+
+	first := &Gadget{Serial: "a", Weight: 1, Label: ptr("first")}
+	outcome, err := ezg.W(first).Upsert(orm, bySerial)
+	if err != nil || outcome != ezg.Inserted || first.ID == 0 {
+		t.Fatalf("expected insert, got %v, %v, %+v", outcome, err, first)
+	}
+	second := &Gadget{Serial: "a", Weight: 2, Label: ptr("second")}
+	if outcome, err = ezg.W(second).Upsert(orm, bySerial); err != nil || outcome != ezg.Updated {
+		t.Fatalf("expected update, got %v, %v", outcome, err)
+	}
+	got, err := ezg.W(&Gadget{}).FindByID(orm, first.ID)
+	if err != nil || second.ID != first.ID || got.Weight != 2 || *got.Label != "second" {
+		t.Fatalf("expected record updated, got %+v, %v", got, err)
+	}
+
+	// only listed columns are updated, conflict target can be named index
+	third := &Gadget{Serial: "a", Weight: 3, Label: ptr("third")}
+	outcome, err = ezg.W(third).Upsert(orm, &ezg.UpsertOptions{Index: "idx_gadgets_serial", Update: []string{"weight"}})
+	if err != nil || outcome != ezg.Updated {
+		t.Fatalf("expected update, got %v, %v", outcome, err)
+	}
+	if got, err = ezg.W(&Gadget{}).FindByID(orm, first.ID); err != nil || got.Weight != 3 || *got.Label != "second" {
+		t.Fatalf("expected only weight updated, got %+v, %v", got, err)
+	}
+
+	// conflict on primary key by default
+	got.Weight = 4
+	if outcome, err = ezg.W(got).Upsert(orm, nil); err != nil || outcome != ezg.Updated {
+		t.Fatalf("expected update, got %v, %v", outcome, err)
+	}
+
+	gadgets := []Gadget{
+		{Serial: "a", Weight: 5, Label: ptr("ignored")},
+		{Serial: "b", Weight: 1, Label: ptr("b")},
+		{Serial: "c", Weight: 1, Label: ptr("c")},
+	}
+	outcomes, err := ezg.UpsertMany(orm, gadgets, &ezg.UpsertOptions{Columns: []string{"serial"}, DoNothing: true, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 3 || outcomes[0] != ezg.Unchanged || outcomes[1] != ezg.Inserted || outcomes[2] != ezg.Inserted {
+		t.Fatalf("unexpected outcomes %v", outcomes)
+	}
+	for _, g := range gadgets {
+		found, err := ezg.W(&Gadget{}).FindByID(orm, g.ID)
+		if err != nil || found == nil || found.Serial != g.Serial {
+			t.Fatalf("expected %s to have key of its record, got %+v, %v", g.Serial, found, err)
+		}
+		if g.Serial == "a" && found.Weight != 4 {
+			t.Fatalf("expected a unchanged, got %+v", found)
+		}
+	}
+
+	if _, err = ezg.W(&Gadget{}).Upsert(orm, &ezg.UpsertOptions{Columns: []string{"nope"}}); !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+	if _, err = ezg.W(&Gadget{}).Upsert(orm, &ezg.UpsertOptions{Index: "idx_nope"}); err == nil {
+		t.Fatal("expected unknown index to fail")
+	}
+	if _, err = ezg.UpsertMany(orm, []Gadget{{Serial: "d", Weight: -1, Label: ptr("d")}}, bySerial); !errors.Is(err, ezg.ErrCheckViolation) {
+		t.Fatalf("expected ErrCheckViolation, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/m8b-dev/gorm-wrap/ezg"
+	"gorm.io/gorm"
+)
+
+func Test_UpdateDeleteWhere(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Task{}); err != nil {
+		t.Fatal(err)
+	}
+	tasks := []Task{
+		{Title: "a", Priority: 1}, {Title: "b", Priority: 1}, {Title: "c", Priority: 2}, {Title: "d", Priority: 3},
+	}
+	if _, err := ezg.InsertMany(orm, tasks, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ezg.W(&Task{Priority: 1}).UpdateWhere(orm, map[string]interface{}{"Done": true})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 updated tasks, got %d, %v", n, err)
+	}
+	n, err = ezg.W(&Task{}).Where(ezg.Gte("priority", 2)).UpdateWhere(orm, map[string]interface{}{
+		"priority": gorm.Expr("priority + ?", 10),
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 updated tasks, got %d, %v", n, err)
+	}
+	n, err = ezg.W(&Task{}).UpdateWhereSql(orm, map[string]interface{}{"note": "late"}, "priority > ? AND done = ?", 12, false)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 updated task, got %d, %v", n, err)
+	}
+	done, err := ezg.W(&Task{Done: true}).Count(orm)
+	if err != nil || done != 2 {
+		t.Fatalf("expected 2 done tasks, got %d, %v", done, err)
+	}
+	d, err := ezg.W(&Task{Title: "d"}).FindOne(orm)
+	if err != nil || d.Priority != 13 || d.Note == nil || *d.Note != "late" {
+		t.Fatalf("unexpected task %+v, %v", d, err)
+	}
+
+	if _, err = ezg.W(&Task{}).UpdateWhere(orm, map[string]interface{}{"done": false}); !errors.Is(err, ezg.ErrNoConditions) {
+		t.Fatalf("expected ErrNoConditions, got %v", err)
+	}
+	if _, err = ezg.W(&Task{}).DeleteWhereSql(orm, ""); !errors.Is(err, ezg.ErrNoConditions) {
+		t.Fatalf("expected ErrNoConditions, got %v", err)
+	}
+	if _, err = ezg.W(&Task{Priority: 1}).UpdateWhere(orm, map[string]interface{}{"nope": 1}); !errors.Is(err, ezg.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+
+	n, err = ezg.W(&Task{Done: true}).DeleteWhere(orm)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 deleted tasks, got %d, %v", n, err)
+	}
+	n, err = ezg.W(&Task{}).DeleteWhereSql(orm, "title = ?", "c")
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 deleted task, got %d, %v", n, err)
+	}
+	// soft deleted tasks are not affected again
+	n, err = ezg.W(&Task{}).AllowAll().UpdateWhere(orm, map[string]interface{}{"priority": 0})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 remaining task to be updated, got %d, %v", n, err)
+	}
+	if n, err = ezg.W(&Task{}).AllowAll().DeleteWhere(orm); err != nil || n != 1 {
+		t.Fatalf("expected 1 remaining task to be deleted, got %d, %v", n, err)
+	}
+	if remaining, err := ezg.W(&Task{}).Count(orm); err != nil || remaining != 0 {
+		t.Fatalf("expected no tasks, got %d, %v", remaining, err)
+	}
+}
+
+// Guarded rejects every update and delete of a loaded record in its hooks, which bulk updates and deletes skip.
+type Guarded struct {
+	gorm.Model
+
+	Name string
+}
+
+var errGuarded = errors.New("guarded")
+
+func (g *Guarded) BeforeUpdate(tx *gorm.DB) error {
+	return errGuarded
+}
+
+func (g *Guarded) BeforeDelete(tx *gorm.DB) error {
+	return errGuarded
+}
+
+func Test_UpdateDeleteWhereSkipHooks(t *testing.T) {
+	orm := openSqlite(t)
+	if err := orm.AutoMigrate(&Guarded{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ezg.W(&Guarded{Name: "a"}).Insert(orm); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ezg.W(&Guarded{Name: "a"}).UpdateWhere(orm, map[string]interface{}{"name": "b"})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 updated record without hooks, got %d, %v", n, err)
+	}
+	n, err = ezg.W(&Guarded{Name: "b"}).DeleteWhere(orm)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 deleted record without hooks, got %d, %v", n, err)
+	}
+}